@@ -0,0 +1,184 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// Addresses are shown to users as bech32 strings (BIP173): the network prefix, a "1"
+// separator, then the version and the 32 byte address in base32 with a 6 character checksum.
+// A typo is caught by the checksum, and an address of another network by its prefix
+
+const (
+	addressVersion byte = 0 // version of the address format
+	bech32Charset       = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	checksumLen         = 6
+	maxBech32Len        = 90 // longest bech32 string
+)
+
+var (
+	// ErrAddressChecksum is returned when an address has a typo
+	ErrAddressChecksum = errors.New("address checksum is invalid")
+	// ErrAddressNetwork is returned when an address is meant for a different network
+	ErrAddressNetwork = errors.New("address is for a different network")
+)
+
+// bech32 checksum generator
+var bech32Gen = [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+// Network returns the prefix of the addresses of this network
+func Network() string {
+	hrp := os.Getenv("_I32COIN_NETWORK")
+	if hrp == "" || hrp != strings.ToLower(hrp) || strings.Contains(hrp, "1") {
+		log.Fatal("blockchain fatal: could not determine network prefix")
+	}
+	for _, c := range hrp {
+		if c < 33 || c > 126 {
+			log.Fatal("blockchain fatal: invalid network prefix, ", hrp)
+		}
+	}
+	return hrp
+}
+
+// EncodeAddress returns the address string of an address on this network
+func EncodeAddress(addr Hash) string {
+	hrp := Network()
+	data := append([]byte{addressVersion}, regroup(addr, 8, 5, true)...)
+	data = append(data, bech32Checksum(hrp, data)...)
+
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, d := range data {
+		sb.WriteByte(bech32Charset[d])
+	}
+	return sb.String()
+}
+
+// DecodeAddress parses an address string, rejecting typos and addresses of other networks
+func DecodeAddress(s string) (Hash, error) {
+	hrp, data, err := decodeBech32(s)
+	if err != nil {
+		return nil, err
+	}
+	if hrp != Network() {
+		return nil, ErrAddressNetwork
+	}
+
+	if len(data) == 0 || data[0] != addressVersion {
+		return nil, errors.New("address version is unknown")
+	}
+	addr := regroup(data[1:], 5, 8, false)
+	if addr == nil || len(addr) != shaHashSize {
+		return nil, errors.New("address has the wrong length")
+	}
+
+	return addr, nil
+}
+
+// splits a bech32 string into its lowercase prefix and data values, checking the checksum
+func decodeBech32(s string) (string, []byte, error) {
+	if len(s) > maxBech32Len {
+		return "", nil, errors.New("address is too long")
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < 33 || s[i] > 126 {
+			return "", nil, fmt.Errorf("address has invalid character %q", s[i])
+		}
+	}
+	lower := strings.ToLower(s)
+	if lower != s && strings.ToUpper(s) != s {
+		return "", nil, errors.New("address has mixed case")
+	}
+
+	sep := strings.LastIndexByte(lower, '1')
+	if sep < 1 || sep+1+checksumLen > len(lower) {
+		return "", nil, errors.New("address is malformed")
+	}
+	hrp := lower[:sep]
+
+	data := make([]byte, 0, len(lower)-sep-1)
+	for _, c := range lower[sep+1:] {
+		d := strings.IndexRune(bech32Charset, c)
+		if d == -1 {
+			return "", nil, fmt.Errorf("address has invalid character %q", c)
+		}
+		data = append(data, byte(d))
+	}
+
+	if bech32Polymod(append(expandHRP(hrp), data...)) != 1 {
+		return "", nil, ErrAddressChecksum
+	}
+	return hrp, data[:len(data)-checksumLen], nil
+}
+
+// computes the bech32 checksum polynomial
+func bech32Polymod(values []byte) uint32 {
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= bech32Gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+// expands the prefix for checksumming
+func expandHRP(hrp string) []byte {
+	expanded := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+	return expanded
+}
+
+// returns the 6 checksum values of the prefix and data
+func bech32Checksum(hrp string, data []byte) []byte {
+	values := append(expandHRP(hrp), data...)
+	values = append(values, make([]byte, checksumLen)...)
+	mod := bech32Polymod(values) ^ 1
+
+	checksum := make([]byte, checksumLen)
+	for i := range checksum {
+		checksum[i] = byte(mod>>uint(5*(5-i))) & 31
+	}
+	return checksum
+}
+
+// regroups bits from groups of from bits into groups of to bits. Without padding, nil is
+// returned if bits are left over
+func regroup(data []byte, from uint, to uint, pad bool) []byte {
+	acc, bits := uint32(0), uint(0)
+	max := uint32(1)<<to - 1
+	out := make([]byte, 0, len(data)*int(from)/int(to)+1)
+
+	for _, v := range data {
+		acc = acc<<from | uint32(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte(acc>>bits&max))
+		}
+	}
+
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(to-bits)&max))
+		}
+	} else if bits >= from || acc<<(to-bits)&max != 0 {
+		return nil
+	}
+
+	return out
+}
@@ -0,0 +1,103 @@
+package blockchain
+
+import (
+	"strings"
+	"testing"
+)
+
+// encodes data values under any prefix
+func encodeBech32(hrp string, data []byte) string {
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, d := range append(data, bech32Checksum(hrp, data)...) {
+		sb.WriteByte(bech32Charset[d])
+	}
+	return sb.String()
+}
+
+// BIP173 valid bech32 strings
+func TestBech32Valid(t *testing.T) {
+	for _, s := range []string{
+		"A12UEL5L",
+		"a12uel5l",
+		"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs",
+		"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw",
+		"11qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqc8247j",
+		"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w",
+		"?1ezyfcl",
+	} {
+		hrp, data, err := decodeBech32(s)
+		if err != nil {
+			t.Errorf("%v rejected, %v", s, err)
+			continue
+		}
+		if encoded := encodeBech32(hrp, data); encoded != strings.ToLower(s) {
+			t.Errorf("%v encoded again as %v", s, encoded)
+		}
+	}
+}
+
+// BIP173 invalid bech32 strings
+func TestBech32Invalid(t *testing.T) {
+	for s, reason := range map[string]string{
+		"\x201nwldj5": "prefix character out of range",
+		"\x7f1axkwrx": "prefix character out of range",
+		"\x801eym55h": "prefix character out of range",
+		"an84characterslonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1569pvx": "too long",
+		"pzry9x0s0muk":  "no separator",
+		"1pzry9x0s0muk": "empty prefix",
+		"x1b4n0q5v":     "invalid data character",
+		"li1dgmt3":      "checksum too short",
+		"de1lg7wt\xff":  "invalid checksum character",
+		"A1G7SGD8":      "checksum of the uppercase prefix",
+		"10a06t8":       "empty prefix",
+		"1qzzfhee":      "empty prefix",
+	} {
+		if _, _, err := decodeBech32(s); err == nil {
+			t.Errorf("%q accepted, %v", s, reason)
+		}
+	}
+}
+
+func TestAddressRoundTrip(t *testing.T) {
+	_, addr := newKey(t)
+	s := EncodeAddress(addr)
+	for _, encoded := range []string{s, strings.ToUpper(s)} {
+		decoded, err := DecodeAddress(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.Equals(addr) {
+			t.Fatalf("%v decoded to another address", encoded)
+		}
+	}
+}
+
+func TestAddressInvalid(t *testing.T) {
+	_, addr := newKey(t)
+	s := EncodeAddress(addr)
+
+	mixed := strings.ToUpper(s[:5]) + s[5:]
+	if _, err := DecodeAddress(mixed); err == nil {
+		t.Error("address with mixed case accepted")
+	}
+
+	typo := []byte(s)
+	last := strings.IndexByte(bech32Charset, typo[len(typo)-1])
+	typo[len(typo)-1] = bech32Charset[(last+1)%len(bech32Charset)]
+	if _, err := DecodeAddress(string(typo)); err != ErrAddressChecksum {
+		t.Errorf("address with a typo rejected with %v, want %v", err, ErrAddressChecksum)
+	}
+
+	data := append([]byte{addressVersion}, regroup(addr, 8, 5, true)...)
+	if _, err := DecodeAddress(encodeBech32("tb", data)); err != ErrAddressNetwork {
+		t.Errorf("address of another network rejected with %v, want %v", err, ErrAddressNetwork)
+	}
+	if _, err := DecodeAddress(encodeBech32(Network(), data[:len(data)-1])); err == nil {
+		t.Error("short address accepted")
+	}
+	if _, err := DecodeAddress(encodeBech32(Network(), append([]byte{1}, data[1:]...))); err == nil {
+		t.Error("address of an unknown version accepted")
+	}
+}
@@ -0,0 +1,105 @@
+package blockchain
+
+import (
+	"log"
+
+	"github.com/JMWorden/int32coin/messages"
+)
+
+// Validates a block at a checkpoint height is the checkpointed block
+func validateCheckpoint(b *Block) error {
+	checkpoint, found := Checkpoints()[b.Height]
+	if !found {
+		return nil
+	}
+
+	hash, err := b.Hash()
+	if err != nil {
+		return invalid(Malformed, "could not hash block, %v", err)
+	}
+	if !hash.Equals(checkpoint) {
+		return invalid(BadCheckpoint, "block %v conflicts with checkpoint", b.Height)
+	}
+	return nil
+}
+
+// returns true if the signatures of the block may be skipped, because it's the assume-valid
+// block or below it. Blocks below it are only assumed to be its ancestors until it's added,
+// and only while the chain hasn't reached its height. Blocks below it after that come from
+// a reorg onto another fork, so they aren't its ancestors and are verified
+func (bc *Blockchain) assumesValid(b *Block) bool {
+	height, hash, ok := AssumeValid()
+	if !ok || b.Height > height {
+		return false
+	}
+	if b.Height < height {
+		return !bc.reached
+	}
+
+	blockHash, err := b.Hash()
+	return err == nil && blockHash.Equals(hash)
+}
+
+// Verifies the signatures of the blocks added without checking them, when a block other
+// than the assume-valid block is added at its height
+func (bc *Blockchain) checkAssumed(b *Block, assumed bool) error {
+	height, _, ok := AssumeValid()
+	if !ok || assumed || b.Height != height || len(bc.assumed) == 0 {
+		return nil
+	}
+
+	log.Println("blockchain: block is not the assume-valid block, verifying signatures")
+	return bc.checkSignatures()
+}
+
+// Verifies the signatures of the blocks added without checking them. Blocks from the first
+// bad one to the top are removed. The assumptions are dropped either way
+func (bc *Blockchain) checkSignatures() error {
+	pending := bc.assumed
+	bc.assumed = nil
+
+	for _, h := range pending {
+		if err := verifySignatures(bc.blocks[h].Transactions[1:]); err != nil {
+			bc.removeBlocks(h)
+			return invalid(BadSignature, "block %v was assumed valid, %v", h, err)
+		}
+	}
+	return nil
+}
+
+// Verifies the blocks added without checking signatures before the chain is used for more
+// than syncing (mining or serving snapshots), since the assume-valid block may never come.
+// Returns the replies announcing removed blocks if one is bad
+func (bc *Blockchain) verifyAssumed() []messages.LocalMsg {
+	if len(bc.assumed) == 0 {
+		return nil
+	}
+
+	top := bc.height
+	log.Println("blockchain: verifying signatures of blocks assumed valid")
+	if err := bc.checkSignatures(); err != nil {
+		log.Println("blockchain: removed blocks assumed valid -- ", err)
+	}
+	if bc.height < top {
+		return []messages.LocalMsg{{Mtype: messages.RemovedBlocks, Height: bc.height + 1}}
+	}
+	return nil
+}
+
+// records a block added without checking signatures, and forgets the assumptions once
+// they're confirmed by the assume-valid block or a checkpoint
+func (bc *Blockchain) settleAssumed(b *Block, assumed bool) {
+	if assumed {
+		bc.assumed = append(bc.assumed, b.Height)
+	}
+
+	height, _, _ := AssumeValid()
+	if b.Height == height {
+		bc.reached = true
+	}
+	_, checkpoint := Checkpoints()[b.Height]
+	if len(bc.assumed) > 0 && (assumed && b.Height == height || checkpoint) {
+		log.Printf("blockchain: signatures up to block %v are valid\n", b.Height)
+		bc.assumed = nil
+	}
+}
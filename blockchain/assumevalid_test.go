@@ -0,0 +1,145 @@
+package blockchain
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/JMWorden/int32coin/messages"
+)
+
+// sets the assume-valid block of the chains created until the test ends
+func setAssumeValid(t *testing.T, height uint64, hash Hash) {
+	t.Helper()
+	os.Setenv("_I32COIN_ASSUME_VALID", fmt.Sprintf("%v:%v", height, hash))
+	t.Cleanup(func() { os.Unsetenv("_I32COIN_ASSUME_VALID") })
+}
+
+// returns the genesis transaction paying 100 to addr and blocks 1 to 3 on top of it, where
+// block 1 spends addr's coins with a forged signature. Built on a chain assuming valid a
+// later block
+func forgedBlocks(t *testing.T, addr Hash) (Transaction, []*Block) {
+	t.Helper()
+	thiefPriv, thief := newKey(t)
+
+	setAssumeValid(t, 100, MessageDigest([]byte("unknown block")))
+	genesis := NewTransaction(RootHash(), addr, 100)
+	builder := NewBlockchain(genesis)
+
+	blocks := make([]*Block, 0, 3)
+	for _, trans := range []Transaction{signedTransfer(t, thiefPriv, addr, thief, 90),
+		signedTransfer(t, thiefPriv, thief, addr, 1), signedTransfer(t, thiefPriv, thief, addr, 1)} {
+		b := nextBlock(t, builder, thief, trans)
+		if err := builder.AddBlock(b); err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, b)
+	}
+	return genesis, blocks
+}
+
+func blockHash(t *testing.T, b *Block) Hash {
+	t.Helper()
+	hash, err := b.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+// the assume-valid block confirms the blocks below it are its ancestors
+func TestAssumeValidConfirmed(t *testing.T) {
+	_, addr := newKey(t)
+	genesis, blocks := forgedBlocks(t, addr)
+
+	setAssumeValid(t, 3, blockHash(t, blocks[2]))
+	bc := NewBlockchain(genesis)
+	for _, b := range blocks {
+		if err := bc.AddBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(bc.assumed) != 0 {
+		t.Fatalf("assumptions %v not settled by the assume-valid block", bc.assumed)
+	}
+}
+
+// another block at the assume-valid height verifies the blocks assumed valid
+func TestAssumeValidOtherBlock(t *testing.T) {
+	_, addr := newKey(t)
+	genesis, blocks := forgedBlocks(t, addr)
+
+	setAssumeValid(t, 3, MessageDigest([]byte("another block")))
+	bc := NewBlockchain(genesis)
+	for _, b := range blocks[:2] {
+		if err := bc.AddBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := bc.AddBlock(blocks[2])
+	if reason := reasonOf(t, err); reason != BadSignature {
+		t.Fatalf("rejected as %v, want %v", reason, BadSignature)
+	}
+	if bc.height != 0 || len(bc.assumed) != 0 {
+		t.Fatalf("chain at %v assuming %v, want the forged blocks removed", bc.height, bc.assumed)
+	}
+}
+
+// once the chain reached the assume-valid height, blocks below it are from another fork
+func TestAssumeValidReorg(t *testing.T) {
+	priv, addr := newKey(t)
+	_, miner := newKey(t)
+	genesis, blocks := forgedBlocks(t, addr)
+
+	setAssumeValid(t, 2, MessageDigest([]byte("another block")))
+	bc := NewBlockchain(genesis)
+	for len(bc.blocks) < 3 {
+		if err := bc.AddBlock(nextBlock(t, bc, miner, signedTransfer(t, priv, addr, miner, 1))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bc.removeBlocks(1)
+	err := bc.AddBlock(blocks[0])
+	if reason := reasonOf(t, err); reason != BadSignature {
+		t.Fatalf("forged block below the assume-valid height rejected as %v, want %v", reason, BadSignature)
+	}
+}
+
+// mining on the chain verifies the blocks assumed valid, the assume-valid block may never come
+func TestAssumeValidCandidate(t *testing.T) {
+	_, addr := newKey(t)
+	genesis, blocks := forgedBlocks(t, addr)
+
+	setAssumeValid(t, 3, blockHash(t, blocks[2]))
+	bc := NewBlockchain(genesis)
+	for _, b := range blocks[:2] {
+		if err := bc.AddBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	replies := bc.handle(messages.LocalMsg{Mtype: messages.GenCandidate})
+	if bc.height != 0 {
+		t.Fatalf("chain at %v, want the forged blocks removed", bc.height)
+	}
+	if len(replies) == 0 || replies[0].Mtype != messages.RemovedBlocks || replies[0].Height != 1 {
+		t.Fatalf("replies %v, want the removal of block 1 announced", replies)
+	}
+}
+
+// the parameters are parsed when the chain is created
+func TestParamsLoadedOnce(t *testing.T) {
+	_, addr := newKey(t)
+	newTestChain(addr, 100)
+
+	setAssumeValid(t, 3, MessageDigest([]byte("block")))
+	if _, _, ok := AssumeValid(); ok {
+		t.Fatal("assume-valid block changed after the chain was created")
+	}
+	newTestChain(addr, 100)
+	if height, _, ok := AssumeValid(); !ok || height != 3 {
+		t.Fatal("assume-valid block not loaded with the chain")
+	}
+}
@@ -8,8 +8,6 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"os"
-	"strconv"
 
 	"golang.org/x/crypto/sha3"
 )
@@ -44,35 +42,18 @@ type Block struct {
 	Nonce        uint64        // value that miners are incrementing
 	PrevHash     Hash          // hash of previous block
 	MerkleRoot   Hash          // merkle root of transaction merkle tree
-	Target       Hash          // hash should be less than this value
+	Bits         uint32        // compact target, hash should be at most this value
 	Transactions []Transaction // transactions in this block
 }
 
 // NewBlock generates a new block wil default nonce. Does not calculate merkle root
 func NewBlock(height uint64, prevHash Hash, transactions []Transaction) *Block {
 	b := Block{Height: height, PrevHash: prevHash, Transactions: transactions}
-	b.Target = makeTarget()
+	b.Bits = TargetBits()
 	return &b
 }
 
-func makeTarget() Hash {
-	diff, err := strconv.Atoi(os.Getenv("_I32COIN_DIFFICULTY"))
-	if err != nil {
-		log.Fatal("fatal: could not determine difficulty")
-	}
-
-	target := make([]byte, shaHashSize)
-	for i := 0; i < shaHashSize; i++ {
-		if i < diff {
-			target[i] = 0xFF
-		} else {
-			target[i] = 0x0
-		}
-	}
-	return target
-}
-
-// Hash double sha3-256 hashs the nonce, previous block hash, target, and merkle root
+// Hash double sha3-256 hashs the nonce, previous block hash, target bits, and merkle root
 func (b *Block) Hash() (Hash, error) {
 	sha := sha3.New256()
 
@@ -82,7 +63,9 @@ func (b *Block) Hash() (Hash, error) {
 	if _, err := sha.Write(b.MerkleRoot); err != nil {
 		return nil, err
 	}
-	if _, err := sha.Write(b.Target); err != nil {
+	bitsBuf := new(bytes.Buffer)
+	binary.Write(bitsBuf, binary.LittleEndian, b.Bits)
+	if _, err := sha.Write(bitsBuf.Bytes()); err != nil {
 		return nil, err
 	}
 
@@ -102,8 +85,8 @@ func (b *Block) Hash() (Hash, error) {
 }
 
 func (b *Block) String() string {
-	return fmt.Sprintf("block %v: \n\tnonce:%v\n\tprevHash:%v\n\troot:%v\n\ttarget:%v\n\ttrans:%v",
-		b.Height, b.Nonce, b.PrevHash, b.MerkleRoot, b.Target, b.Transactions)
+	return fmt.Sprintf("block %v: \n\tnonce:%v\n\tprevHash:%v\n\troot:%v\n\tbits:%08x\n\ttrans:%v",
+		b.Height, b.Nonce, b.PrevHash, b.MerkleRoot, b.Bits, b.Transactions)
 }
 
 // Send encodes Block and transmits to io.Writer (assumedly the network)
@@ -131,7 +114,7 @@ func Recv(r io.Reader) (*Block, error) {
 	return &b, err
 }
 
-// HashOk returns true if hash is less than target hash
+// HashOk returns true if the hash, as a little-endian integer, is at most the target
 func (b *Block) HashOk() (bool, error) {
 	hash, err := b.Hash()
 	if err != nil {
@@ -139,13 +122,10 @@ func (b *Block) HashOk() (bool, error) {
 		return false, err
 	}
 
-	ok := true
-	for i := len(hash) - 1; i > -1; i-- {
-		if hash[i] > b.Target[i] {
-			ok = false
-			break
-		}
+	target := b.Target()
+	if target == nil {
+		return false, nil
 	}
 
-	return ok, nil
+	return HashToBig(hash).Cmp(target) <= 0, nil
 }
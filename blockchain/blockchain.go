@@ -1,11 +1,10 @@
 package blockchain
 
 import (
-	"errors"
-	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"sync"
 
 	"github.com/JMWorden/int32coin/messages"
 )
@@ -15,15 +14,38 @@ const shaHashSize int = 32
 
 // Blockchain is the main structure that references all the blocks and contains global info
 type Blockchain struct {
-	height uint64            // number of blocks in the block chain
-	blocks map[uint64]*Block // blocks in the block chain, indexed by height
-	queued []Transaction     // transactions not in any block
+	mu       sync.RWMutex          // guards everything below, Listen holds it while handling a message
+	height   uint64                // number of blocks in the block chain
+	base     uint64                // height of the snapshot the chain was loaded from, 0 if none
+	blocks   map[uint64]*Block     // blocks in the block chain, indexed by height
+	filters  map[uint64]*Filter    // compact filters of the blocks, indexed by height
+	channels map[string]*Channel   // payment channels opened in the chain, indexed by id
+	balances map[string]int64      // account balances at the top of the chain, indexed by address
+	txids    map[string]struct{}   // TXIDs used in the chain
+	undo     map[uint64]*blockUndo // account state changes of the blocks, indexed by height
+	assumed  []uint64              // heights of blocks added without checking signatures
+	reached  bool                  // true once a block at the assume-valid height was added
+	index    *index                // optional lookup indexes
+	queued   []Transaction         // transactions not in any block
+	queuedID map[string]struct{}   // IDs of queued transactions
 }
 
 // NewBlockchain creates a new block chain with genesis block
 func NewBlockchain(first Transaction) *Blockchain {
+	loadParams()
+
 	bc := Blockchain{height: 0, blocks: make(map[uint64]*Block), queued: make([]Transaction, 0, initQLen)}
+	bc.filters = make(map[uint64]*Filter)
+	bc.queuedID = make(map[string]struct{})
+	bc.channels = make(map[string]*Channel)
+	bc.balances = make(map[string]int64)
+	bc.txids = make(map[string]struct{})
+	bc.undo = make(map[uint64]*blockUndo)
+	bc.index = newIndex()
 	bc.blocks[0] = genesisBlock(first)
+	bc.addFilter(bc.blocks[0])
+	bc.applyBlock(bc.blocks[0])
+	bc.index.add(bc.blocks[0])
 	return &bc
 }
 
@@ -31,7 +53,7 @@ func NewBlockchain(first Transaction) *Blockchain {
 func genesisBlock(first Transaction) *Block {
 	gen := Block{Height: 0, PrevHash: make([]byte, 32), Transactions: make([]Transaction, 1)}
 	gen.Transactions[0] = first
-	gen.Target = makeTarget()
+	gen.Bits = TargetBits()
 
 	root, err := CalcMerkleRoot(gen.Transactions)
 	if err != nil {
@@ -42,53 +64,131 @@ func genesisBlock(first Transaction) *Block {
 	return &gen
 }
 
-// Listen listens for messages and processes them
+// Listen listens for messages and processes them. Replies are sent after releasing the
+// lock, so readers aren't blocked by a full out channel
 func (bc *Blockchain) Listen(in <-chan messages.LocalMsg, out chan<- messages.LocalMsg) {
 	for msg := range in {
-		switch msg.Mtype {
-		case messages.AddBlock:
-			log.Println("blockchain: inspecting block ", msg.Block.(*Block).Height)
-			added := bc.addBlock(msg.Block.(*Block))
-			if added {
-				log.Printf("blockchain: sharing block")
-				out <- messages.LocalMsg{Mtype: messages.ShareBlock, Block: bc.Top()}
-			} else {
-				log.Printf("blockchain: skipping block")
-			}
-			if len(bc.queued) > 0 {
-				// generate candidate block with remaining transactions
-				b := bc.CandidateBlock()
-				log.Println("blockchain: sending candidate")
-				out <- messages.LocalMsg{Mtype: messages.CandidateBlock, Block: b}
+		bc.mu.Lock()
+		replies := bc.handle(msg)
+		bc.mu.Unlock()
+
+		for _, reply := range replies {
+			out <- reply
+		}
+	}
+}
+
+// processes a message, returning the replies. Must hold the write lock
+func (bc *Blockchain) handle(msg messages.LocalMsg) []messages.LocalMsg {
+	replies := make([]messages.LocalMsg, 0, 2)
+
+	switch msg.Mtype {
+	case messages.AddBlock:
+		log.Println("blockchain: inspecting block ", msg.Block.(*Block).Height)
+		top := bc.height
+		err := bc.addBlock(msg.Block.(*Block))
+		if err == nil {
+			log.Printf("blockchain: sharing block")
+			replies = append(replies, messages.LocalMsg{Mtype: messages.ShareBlock, Block: bc.top()})
+		} else {
+			log.Println("blockchain: skipping bad block -- ", err)
+			replies = append(replies, messages.LocalMsg{Mtype: messages.RejectBlock, Block: msg.Block,
+				Peer: msg.Peer, Err: err})
+			if bc.height < top {
+				replies = append(replies, messages.LocalMsg{Mtype: messages.RemovedBlocks, Height: bc.height + 1})
 			}
-			break
-		case messages.Transaction:
-			bc.Enqueue(msg.Transaction.(Transaction))
-			break
-		case messages.GenCandidate:
-			b := bc.CandidateBlock()
+		}
+		if len(bc.queued) > 0 && len(bc.assumed) == 0 {
+			// generate candidate block with remaining transactions, not on unverified blocks
+			b := bc.candidateBlock()
 			log.Println("blockchain: sending candidate")
-			out <- messages.LocalMsg{Mtype: messages.CandidateBlock, Block: b}
-			break
-		case messages.RemoveBlocks:
-			bc.removeBlocks(msg.Height)
-			break
-		case messages.RangeReq:
-			out <- messages.LocalMsg{Mtype: messages.Range, Block: bc.getRange(msg.Height)}
-			break
+			replies = append(replies, messages.LocalMsg{Mtype: messages.CandidateBlock, Block: b})
+		}
+		break
+	case messages.Transaction:
+		bc.enqueue(msg.Transaction.(Transaction))
+		break
+	case messages.GenCandidate:
+		replies = append(replies, bc.verifyAssumed()...)
+		b := bc.candidateBlock()
+		log.Println("blockchain: sending candidate")
+		replies = append(replies, messages.LocalMsg{Mtype: messages.CandidateBlock, Block: b})
+		break
+	case messages.RemoveBlocks:
+		bc.removeBlocks(msg.Height)
+		break
+	case messages.RangeReq:
+		blocks, err := bc.getRange(msg.Height)
+		if err != nil {
+			log.Println("blockchain: refusing range request -- ", err)
+		}
+		replies = append(replies, messages.LocalMsg{Mtype: messages.Range, Block: blocks, Err: err})
+		break
+	case messages.SnapshotReq:
+		replies = append(replies, bc.verifyAssumed()...)
+		s, err := bc.snapshot(msg.Height)
+		if err != nil {
+			log.Println("blockchain: refusing snapshot request -- ", err)
+		} else if hash, err := s.Hash(); err == nil {
+			log.Printf("blockchain: snapshot at %v has hash %v\n", s.Height, hash)
+		}
+		replies = append(replies, messages.LocalMsg{Mtype: messages.Snapshot, Snapshot: s, Peer: msg.Peer,
+			Err: err})
+		break
+	case messages.LoadSnapshot:
+		err := bc.loadSnapshot(msg.Snapshot.(*Snapshot))
+		if err != nil {
+			log.Println("blockchain: skipping bad snapshot -- ", err)
 		}
+		replies = append(replies, messages.LocalMsg{Mtype: messages.SnapshotLoaded, Block: bc.top(),
+			Peer: msg.Peer, Err: err})
+		break
+	case messages.FilterReq:
+		replies = append(replies, messages.LocalMsg{Mtype: messages.Filters, Filters: bc.getFilters(msg.Height)})
+		break
+	case messages.FilterHeaderReq:
+		replies = append(replies, messages.LocalMsg{Mtype: messages.FilterHeaders, Height: msg.Height,
+			Filters: bc.getFilterHeaders(msg.Height)})
+		break
 	}
+
+	return replies
 }
 
 func (bc *Blockchain) removeBlocks(first uint64) {
-	for h := first; h <= bc.height; h++ {
+	if first <= bc.base {
+		log.Println("blockchain: can't remove blocks below the snapshot at ", bc.base)
+		return
+	}
+	if cp := LastCheckpoint(bc.height); first <= cp {
+		log.Println("blockchain: can't remove blocks below the checkpoint at ", cp)
+		return
+	}
+	for h := bc.height; h >= first; h-- {
 		log.Println("blockchain: removing block ", h)
+		bc.revertBlock(h)
+		bc.index.remove(bc.blocks[h])
 		delete(bc.blocks, h)
+		delete(bc.filters, h)
 	}
 	bc.height = first - 1
+
+	for ndx, h := range bc.assumed {
+		if h >= first {
+			bc.assumed = bc.assumed[:ndx]
+			break
+		}
+	}
 }
 
-func (bc *Blockchain) getRange(first uint64) []*Block {
+// returns the blocks from first to the top, or an error if some of them were pruned
+func (bc *Blockchain) getRange(first uint64) ([]*Block, error) {
+	if err := bc.checkServed(first); err != nil {
+		return nil, err
+	}
+	if first > bc.height {
+		return []*Block{}, nil
+	}
 	blocks := make([]*Block, bc.height-first+1)
 
 	for h, ndx := first, 0; h <= bc.height; h++ {
@@ -96,7 +196,60 @@ func (bc *Blockchain) getRange(first uint64) []*Block {
 		ndx++
 	}
 
-	return blocks
+	return blocks, nil
+}
+
+// builds the filter for a block just added to the top of the chain, chaining its header
+func (bc *Blockchain) addFilter(b *Block) {
+	f, err := NewFilter(b)
+	if err != nil {
+		log.Fatal("blockchain fatal: failed to create filter: ", err)
+	}
+
+	prev := RootHash()
+	if b.Height > 0 {
+		prev = bc.filters[b.Height-1].Header
+	}
+	f.Header, err = f.CalcHeader(prev)
+	if err != nil {
+		log.Fatal("blockchain fatal: failed to create filter header: ", err)
+	}
+
+	bc.filters[b.Height] = f
+}
+
+func (bc *Blockchain) getFilters(first uint64) []*Filter {
+	if bc.base > 0 && first <= bc.base {
+		first = bc.base + 1 // the snapshot block only has a filter header
+	}
+	if first > bc.height {
+		return []*Filter{}
+	}
+	filters := make([]*Filter, bc.height-first+1)
+
+	for h, ndx := first, 0; h <= bc.height; h++ {
+		filters[ndx] = bc.filters[h]
+		ndx++
+	}
+
+	return filters
+}
+
+func (bc *Blockchain) getFilterHeaders(first uint64) []Hash {
+	if first < bc.base {
+		first = bc.base
+	}
+	if first > bc.height {
+		return []Hash{}
+	}
+	headers := make([]Hash, bc.height-first+1)
+
+	for h, ndx := first, 0; h <= bc.height; h++ {
+		headers[ndx] = bc.filters[h].Header
+		ndx++
+	}
+
+	return headers
 }
 
 // RootHash returns all 0 hash; used for rewards and default signature
@@ -104,87 +257,174 @@ func RootHash() Hash {
 	return Hash(make([]byte, shaHashSize))
 }
 
-// Top returns top of blockchain
-func (bc *Blockchain) Top() *Block {
+// returns top of blockchain, must hold the lock
+func (bc *Blockchain) top() *Block {
 	return bc.blocks[bc.height]
 }
 
 // Enqueue validates and enqueues a transaction to be added to the block chain
 func (bc *Blockchain) Enqueue(t Transaction) error {
-	t.Seq = uint32(len(bc.queued)) + 1
-	err := bc.validateTransaction(t, bc.queued)
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	return bc.enqueue(t)
+}
+
+// enqueue is Enqueue without locking, must hold the write lock
+func (bc *Blockchain) enqueue(t Transaction) error {
+	id, err := t.ID()
+
+	if err == nil {
+		if _, found := bc.queuedID[string(id)]; found {
+			err = invalid(DuplicateTXID, "transaction already queued")
+		}
+	}
+
+	if err == nil {
+		err = bc.validateTransaction(t, bc.queued, true)
+	}
 
 	if err != nil {
 		log.Println("blockchain: queue rejects bad transaction: ", err)
 	} else {
 		bc.queued = append(bc.queued, t)
-		log.Println("blockchain: queued transaction #", t.Seq)
+		bc.queuedID[string(id)] = struct{}{}
+		log.Println("blockchain: queued transaction ", id)
 	}
 
 	return err
 }
 
-// Validates sender has sufficient balance and transaction was properly signed
-func (bc *Blockchain) validateTransaction(t Transaction, external []Transaction) error {
-	err := bc.validateBalance(t.Sender, t.Amount, t.Seq, t.TXID, external)
+// Validates sender has sufficient balance and, if sigs is set, transaction was properly signed
+func (bc *Blockchain) validateTransaction(t Transaction, external []Transaction, sigs bool) error {
+	var err error = nil
+	if t.IsCoinbase() {
+		err = invalid(BadReward, "reward outside of first position in block")
+	} else if len(t.TXID) == shaHashSize {
+		err = invalid(Malformed, "TXID has the length reserved for coinbases")
+	} else {
+		err = validateUnused(t)
+	}
+
+	if err == nil && t.Kind != Transfer {
+		err = bc.validateChannel(t, external)
+	}
 
 	if err == nil {
-		err = t.ValidateSignature()
+		err = bc.validateBalance(t, external)
+	}
+
+	if err == nil && sigs {
+		err = validateSignatures(t)
 	}
 
 	if err == nil && t.Sender.Equals(t.Reciever) {
-		err = errors.New("sender and reciever are the same")
+		err = invalid(SelfTransfer, "sender and reciever are the same")
 	}
 
 	return err
 }
 
-// Validates sender has sufficient balance (looks at block history and queue), and
+// Validates the transaction and, for a channel close, its closing state were signed
+func validateSignatures(t Transaction) error {
+	if err := t.ValidateSignature(); err != nil {
+		return err
+	}
+	if t.Kind == ChannelClose {
+		return validateStateSig(t)
+	}
+	return nil
+}
+
+// Validates the transaction leaves empty the fields its kind and key types don't use. The
+// ID and merkle leaf don't commit to them, anything in them could be changed in a block
+// without changing its hash
+func validateUnused(t Transaction) error {
+	if t.KeyType == ECDSA && len(t.PubKey) != 0 {
+		return invalid(Malformed, "ecdsa transaction carries a public key")
+	}
+
+	switch t.Kind {
+	case Transfer:
+		if len(t.Channel) != 0 || t.Expiry != 0 {
+			return invalid(Malformed, "transfer carries channel fields")
+		}
+		break
+	case ChannelOpen:
+		if len(t.Channel) != 0 {
+			return invalid(Malformed, "channel open carries a channel id")
+		}
+		break
+	case ChannelClose:
+		if t.Expiry != 0 {
+			return invalid(Malformed, "channel close carries an expiry")
+		}
+		if t.StateKey == ECDSA && len(t.StatePub) != 0 {
+			return invalid(Malformed, "ecdsa channel state carries a public key")
+		}
+		return nil
+	case ChannelRefund:
+		if t.Expiry != 0 {
+			return invalid(Malformed, "channel refund carries an expiry")
+		}
+		break
+	default:
+		return invalid(Malformed, "transaction kind %v is unknown", t.Kind)
+	}
+
+	// only channel closes carry a state
+	if len(t.StateSig) != 0 || t.StateKey != ECDSA || len(t.StatePub) != 0 {
+		return invalid(Malformed, "%v carries a channel state", t.Kind)
+	}
+	return nil
+}
+
+// Validates sender has sufficient balance (looks at the account state and queue), and
 // that the transaction ID is unqiue for the sender
-func (bc *Blockchain) validateBalance(sender Hash, amount uint32, seq uint32, txid Hash,
-	external []Transaction) error {
-	var bal int64 = 0
+func (bc *Blockchain) validateBalance(t Transaction, external []Transaction) error {
 	var err error = nil
+	sender := t.Sender
+	bal := bc.balances[string(sender)]
+	amount := -bc.balanceChange(t, sender) // channel settlements don't spend
 
-	for h := uint64(0); h <= bc.height; h++ {
-		block := bc.blocks[h]
-		for _, trans := range block.Transactions {
-			if trans.Sender.Equals(sender) {
-				bal -= int64(trans.Amount)
-			} else if trans.Reciever.Equals(sender) {
-				bal += int64(trans.Amount)
-			}
+	id, err := t.ID()
+	if err != nil {
+		return invalid(Malformed, "could not hash transaction, %v", err)
+	}
 
-			if trans.TXID.Equals(txid) {
-				return errors.New("TXID was repeated")
-			}
-		}
+	if _, found := bc.txids[string(t.TXID)]; found {
+		return invalid(DuplicateTXID, "TXID was repeated")
 	}
 
 	for _, trans := range external {
-		if trans.Seq == seq { // ignore this transaction
-			continue // TODO : why is this transaction even in the queue yet?
+		transID, err := trans.ID()
+		if err != nil {
+			return invalid(Malformed, "could not hash transaction, %v", err)
 		}
-		if trans.Sender.Equals(sender) {
-			bal -= int64(trans.Amount)
-		} else if trans.Reciever.Equals(sender) {
-			bal += int64(trans.Amount)
+		if transID.Equals(id) { // ignore this transaction (in its own block)
+			continue
 		}
+		bal += bc.balanceChange(trans, sender)
 
-		if trans.TXID.Equals(txid) {
-			return errors.New("TXID was repeated")
+		if trans.TXID.Equals(t.TXID) {
+			return invalid(DuplicateTXID, "TXID was repeated")
 		}
 	}
 
-	if bal < int64(amount) {
-		str := fmt.Sprintf("balance is %v, tried to send %v", bal, amount)
-		err = errors.New(str)
+	if bal < amount {
+		err = invalid(InsufficientBalance, "balance is %v, tried to send %v", bal, amount)
 	}
 	return err
 }
 
 // CandidateBlock copies queue into a new block and returns the block
 func (bc *Blockchain) CandidateBlock() *Block {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.candidateBlock()
+}
+
+// candidateBlock is CandidateBlock without locking, must hold the lock
+func (bc *Blockchain) candidateBlock() *Block {
 	top := bc.blocks[bc.height]
 	prevHash, err := top.Hash()
 	if err != nil {
@@ -193,31 +433,60 @@ func (bc *Blockchain) CandidateBlock() *Block {
 
 	qCopy := make([]Transaction, len(bc.queued))
 	copy(qCopy, bc.queued)
+	for ndx := range qCopy {
+		qCopy[ndx].Seq = uint32(ndx) + 1 // position after the reward
+	}
 
 	return NewBlock(bc.height+1, prevHash, qCopy)
 }
 
-// addBlock validates integrity of block, adding to blockchain if legitimate
-func (bc *Blockchain) addBlock(b *Block) bool {
-	added := false
+// AddBlock validates integrity of block, adding to blockchain if legitimate. Returns a
+// *ValidationError with the reason the block was rejected
+func (bc *Blockchain) AddBlock(b *Block) error {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	return bc.addBlock(b)
+}
 
+// addBlock is AddBlock without locking, must hold the write lock
+func (bc *Blockchain) addBlock(b *Block) error {
 	if b.Height != bc.height+1 {
-		log.Println("blockchain: block height mismatch")
-	} else {
-		ok, err := b.HashOk()
-		if !ok {
-			log.Println("blockchain: block hash not ok")
-		}
-		if ok && err == nil && bc.valuesOk(b) && bc.transactionsOk(b) {
-			bc.height++
-			bc.blocks[bc.height] = b
-			bc.purgeQueued(b.Transactions)
-			added = true
-			log.Printf("blockchain: added block %v\n", bc.height)
-		}
+		return invalid(BadHeight, "block height is %v, expected %v", b.Height, bc.height+1)
+	}
+
+	ok, err := b.HashOk()
+	if err != nil {
+		return invalid(Malformed, "could not hash block, %v", err)
+	}
+	if !ok {
+		return invalid(BadPoW, "block hash not below target")
+	}
+	if err := validateCheckpoint(b); err != nil {
+		return err
 	}
 
-	return added
+	if err := bc.validateValues(b); err != nil {
+		return err
+	}
+	assumed := bc.assumesValid(b)
+	if err := bc.validateTransactions(b, !assumed); err != nil {
+		return err
+	}
+	if err := bc.checkAssumed(b, assumed); err != nil {
+		return err
+	}
+
+	bc.height++
+	bc.blocks[bc.height] = b
+	bc.addFilter(b)
+	bc.applyBlock(b)
+	bc.index.add(b)
+	bc.settleAssumed(b, assumed)
+	bc.prune()
+	bc.purgeQueued(b.Transactions)
+	log.Printf("blockchain: added block %v\n", bc.height)
+
+	return nil
 }
 
 func (bc *Blockchain) purgeQueued(transactions []Transaction) {
@@ -225,102 +494,111 @@ func (bc *Blockchain) purgeQueued(transactions []Transaction) {
 
 	// visit transactions that were just added to the block chain
 	for _, trans := range transactions {
-		included[trans.String()] = true
+		id, err := trans.ID()
+		if err == nil {
+			included[string(id)] = true
+		}
 	}
 
 	oldQueue := bc.queued
 	bc.queued = make([]Transaction, 0, len(oldQueue))
+	bc.queuedID = make(map[string]struct{}, len(oldQueue))
 
 	// add transactions that still aren't in any block and are valid
-	seq := uint32(1)
 	for _, trans := range oldQueue {
-		_, found := included[trans.String()]
-		if !found {
-			err := bc.validateTransaction(trans, bc.queued)
-			if err == nil {
-				trans.Seq = seq
-				bc.queued = append(bc.queued, trans)
-				seq++
-			}
+		id, err := trans.ID()
+		if err != nil || included[string(id)] {
+			continue
+		}
+		err = bc.validateTransaction(trans, bc.queued, true)
+		if err == nil {
+			bc.queued = append(bc.queued, trans)
+			bc.queuedID[string(id)] = struct{}{}
 		}
 	}
 
 	log.Printf("blockchain: keeping %d transactions after adding block", len(bc.queued))
 }
 
-// Returns true if target and previous hash match expected
-func (bc *Blockchain) valuesOk(b *Block) bool {
-	ok := true
+// Validates target and previous hash match expected
+func (bc *Blockchain) validateValues(b *Block) error {
 	top := bc.blocks[bc.height]
 	prevHash, err := top.Hash()
 	if err != nil {
 		log.Fatal("blockchain fatal:", err)
-		ok = false
 	}
 
 	// validate previous hash is the same
-	if ok {
-		ok = b.PrevHash.Equals(prevHash)
-		if !ok {
-			log.Println("blockchain: bad block -- previous block hash mismatch")
-		}
+	if !b.PrevHash.Equals(prevHash) {
+		return invalid(BadPrevHash, "previous block hash mismatch")
 	}
 
 	// validate target is the same
-	if ok {
-		ok = b.Target.Equals(makeTarget())
-		if !ok {
-			log.Println("blockchain: bad block -- target hash mismatch")
-		}
+	if b.Bits != TargetBits() {
+		return invalid(BadTarget, "target bits are %08x, expected %08x", b.Bits, TargetBits())
 	}
 
-	return ok
+	return nil
 }
 
-func (bc *Blockchain) transactionsOk(b *Block) bool {
-	ok := true
+// Validates the merkle root, the reward, and every other transaction of the block. Signatures
+// are only checked if sigs is set
+func (bc *Blockchain) validateTransactions(b *Block, sigs bool) error {
 	root, err := CalcMerkleRoot(b.Transactions)
 	if err != nil {
-		log.Println("blockchain: bad block -- ", err)
-		ok = false
+		return invalid(Malformed, "could not calculate merkle root, %v", err)
 	}
 
-	if ok { // validate the merkle root
-		ok = root.Equals(b.MerkleRoot)
-		if !ok {
-			log.Println("blockchain: bad transaction -- merkle root mismatch")
-		}
+	// validate the merkle root
+	if !root.Equals(b.MerkleRoot) {
+		return invalid(BadMerkleRoot, "merkle root mismatch")
 	}
 
-	if ok { // validate each transaction
-		for _, trans := range b.Transactions {
-			if trans.Seq == 0 {
-				continue // skip the reward
-			}
-			err := bc.validateTransaction(trans, b.Transactions)
-			if err != nil {
-				log.Printf("blockchain: bad transaction (#%v) -- %v", trans.Seq, err)
-				ok = false
-				break
-			}
+	if b.Height != 0 && len(b.Transactions) < 2 {
+		return invalid(EmptyBlock, "empty transactions (ignoring reward)")
+	}
+
+	// validate the reward, the only transaction that isn't signed
+	if err := validateCoinbase(b); err != nil {
+		return err
+	}
+
+	// validate each transaction appears once
+	seen := make(map[string]struct{}, len(b.Transactions))
+	for _, trans := range b.Transactions {
+		id, err := trans.ID()
+		if err != nil {
+			return invalid(Malformed, "could not hash transaction, %v", err)
 		}
+		if _, found := seen[string(id)]; found {
+			return invalid(DuplicateTXID, "transaction %v repeated in block", id)
+		}
+		seen[string(id)] = struct{}{}
 	}
 
-	if b.Height != 0 && len(b.Transactions) < 2 {
-		log.Println("blockchain: bad block -- empty transactions (ignoring reward)")
-		ok = false
+	// validate each transaction after the reward, in order. Signatures are checked after,
+	// in parallel
+	for ndx, trans := range b.Transactions {
+		if trans.Seq != uint32(ndx) {
+			return invalid(BadSequence, "transaction #%v has sequence number %v", ndx, trans.Seq)
+		}
+		if ndx == 0 {
+			continue // skip the reward
+		}
+		if err := bc.validateTransaction(trans, b.Transactions, false); err != nil {
+			log.Printf("blockchain: bad transaction (#%v) -- %v", trans.Seq, err)
+			return err
+		}
 	}
 
-	if ok { // validate the reward
-		reward := b.Transactions[0]
-		ok = reward.Sender.Equals(RootHash()) && reward.Signature.Equals(RootHash()) &&
-			reward.Amount == RewardAmount()
-		if !ok {
-			log.Println("blockchain: bad block -- reward incorrect")
+	if sigs {
+		if err := verifySignatures(b.Transactions[1:]); err != nil {
+			log.Println("blockchain: bad transaction signature -- ", err)
+			return err
 		}
 	}
 
-	return ok
+	return nil
 }
 
 // RewardAmount returns expected reward amount
@@ -0,0 +1,105 @@
+package blockchain
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	os.Setenv("_I32COIN_NETWORK", "i32")
+	os.Setenv("_I32COIN_REWARD", "50")
+	os.Setenv("_I32COIN_BITS", "207fffff") // about every other nonce is below the target
+	os.Exit(m.Run())
+}
+
+// returns an ecdsa private key and its address
+func newKey(t *testing.T) (Hash, Hash) {
+	t.Helper()
+	priv, pub, err := ecdsaScheme{}.NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv, Address(ECDSA, pub)
+}
+
+// returns a chain whose genesis pays funds to addr
+func newTestChain(addr Hash, funds uint32) *Blockchain {
+	return NewBlockchain(NewTransaction(RootHash(), addr, funds))
+}
+
+// returns a signed transfer
+func signedTransfer(t *testing.T, priv Hash, from Hash, to Hash, amount uint32) Transaction {
+	t.Helper()
+	trans := NewTransaction(from, to, amount)
+	if err := trans.Sign(priv); err != nil {
+		t.Fatal(err)
+	}
+	return trans
+}
+
+// returns a block on top of bc with a coinbase paying miner and the transactions, numbered
+// and mined
+func nextBlock(t *testing.T, bc *Blockchain, miner Hash, transactions ...Transaction) *Block {
+	t.Helper()
+	reward := NewCoinbase(bc.height+1, miner)
+	reward.Amount += uint32(Fees(transactions))
+	return mineBlock(t, bc, append([]Transaction{reward}, transactions...))
+}
+
+// numbers the transactions and mines a block of them on top of bc, without checking them
+func mineBlock(t *testing.T, bc *Blockchain, transactions []Transaction) *Block {
+	t.Helper()
+	for ndx := range transactions {
+		transactions[ndx].Seq = uint32(ndx)
+	}
+
+	prevHash, err := bc.top().Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewBlock(bc.height+1, prevHash, transactions)
+	if b.MerkleRoot, err = CalcMerkleRoot(transactions); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		ok, err := b.HashOk()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			return b
+		}
+		b.Nonce++
+	}
+}
+
+// returns the reason of a validation error, fails if err isn't one
+func reasonOf(t *testing.T, err error) Reason {
+	t.Helper()
+	reason, ok := ReasonOf(err)
+	if !ok {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	return reason
+}
+
+func TestAddBlock(t *testing.T) {
+	priv, addr := newKey(t)
+	_, other := newKey(t)
+	bc := newTestChain(addr, 100)
+
+	if err := bc.AddBlock(nextBlock(t, bc, other, signedTransfer(t, priv, addr, other, 40))); err != nil {
+		t.Fatal(err)
+	}
+	if bal := bc.Balance(addr); bal != 60 {
+		t.Errorf("sender balance is %v, want 60", bal)
+	}
+	if bal := bc.Balance(other); bal != 40+50 {
+		t.Errorf("reciever balance is %v, want 90", bal)
+	}
+
+	overspend := nextBlock(t, bc, other, signedTransfer(t, priv, addr, other, 61))
+	if err := bc.AddBlock(overspend); err == nil {
+		t.Error("block spending more than the balance added")
+	}
+}
@@ -0,0 +1,256 @@
+package blockchain
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// TxKind is the kind of a transaction, plain transfers are the zero value
+type TxKind uint8
+
+const (
+	// Transfer moves Amount from sender to reciever
+	Transfer TxKind = iota
+	// ChannelOpen locks Amount from sender (funder) in a channel paying reciever (payee)
+	ChannelOpen
+	// ChannelClose settles a channel with the latest state, Amount goes to the payee and the
+	// rest of the deposit back to the funder. Sender is the closing party, the state is
+	// signed by the other party: the funder's payment, or the payee's acknowledgement
+	ChannelClose
+	// ChannelRefund returns the deposit of an expired, unsettled channel to the funder
+	ChannelRefund
+)
+
+func (k TxKind) String() string {
+	switch k {
+	case Transfer:
+		return "transfer"
+	case ChannelOpen:
+		return "channel-open"
+	case ChannelClose:
+		return "channel-close"
+	case ChannelRefund:
+		return "channel-refund"
+	default:
+		return "undefined"
+	}
+}
+
+// Channel is a unidirectional payment channel opened on the block chain
+type Channel struct {
+	ID      Hash   // ID of the open transaction
+	Funder  Hash   // address that locked the deposit
+	Payee   Hash   // address being paid through the channel
+	Deposit uint32 // amount locked in the channel
+	Expiry  uint64 // height from which the funder may refund the deposit
+	Settled bool   // true once closed or refunded
+}
+
+// ChannelState is an off-chain balance update, the total paid through a channel so far.
+// The funder signs states to pay the payee, the payee signs its final state to let the
+// funder close
+type ChannelState struct {
+	Channel   Hash    // channel id
+	Paid      uint32  // total amount paid to the payee
+	Signature Hash    // signature of the funder or payee
+	KeyType   KeyType // signature scheme of the signer's key
+	PubKey    Hash    // public key of the signer, for schemes that can't recover it
+}
+
+// NewChannelOpen generates an unsigned transaction locking deposit in a new channel.
+// The channel id is the ID of this transaction
+func NewChannelOpen(funder Hash, payee Hash, deposit uint32, expiry uint64) Transaction {
+	t := NewTransaction(funder, payee, deposit)
+	t.Kind = ChannelOpen
+	t.Expiry = expiry
+	return t
+}
+
+// NewChannelClose generates an unsigned transaction settling a channel with the state
+// signed by counterparty (the other party of the channel)
+func NewChannelClose(closer Hash, counterparty Hash, state ChannelState) Transaction {
+	t := NewTransaction(closer, counterparty, state.Paid)
+	t.Kind = ChannelClose
+	t.Channel = state.Channel
+	t.StateSig = state.Signature
+	t.StateKey = state.KeyType
+	t.StatePub = state.PubKey
+	return t
+}
+
+// NewChannelRefund generates an unsigned transaction returning an expired channel's deposit
+func NewChannelRefund(funder Hash, payee Hash, channel Hash, deposit uint32) Transaction {
+	t := NewTransaction(funder, payee, deposit)
+	t.Kind = ChannelRefund
+	t.Channel = channel
+	return t
+}
+
+// only (double sha3-256) hashes the channel and the amount paid
+func (s *ChannelState) digest() (Hash, error) {
+	sha := sha3.New256()
+	if _, err := sha.Write([]byte(fmt.Sprintf("channel-state,%v,%v", s.Channel, s.Paid))); err != nil {
+		return nil, err
+	}
+
+	first := sha.Sum(nil)
+	sha = sha3.New256()
+	if _, err := sha.Write(first); err != nil {
+		return nil, err
+	}
+
+	return sha.Sum(nil), nil
+}
+
+// Sign generates signature for the state digest (channel and amount paid) with the
+// scheme of the state's key type
+func (s *ChannelState) Sign(priv Hash) error {
+	scheme, err := Scheme(s.KeyType)
+	if err != nil {
+		return err
+	}
+
+	if s.KeyType != ECDSA {
+		s.PubKey, err = scheme.Public(priv)
+		if err != nil {
+			return err
+		}
+	}
+
+	digest, err := s.digest()
+	if err != nil {
+		return err
+	}
+
+	sig, err := scheme.Sign(digest, priv)
+	if err != nil {
+		return err
+	}
+	if err := scheme.Canonical(sig); err != nil {
+		return err
+	}
+	s.Signature = sig
+
+	return nil
+}
+
+// Signer returns the address that signed the state
+func (s *ChannelState) Signer() (Hash, error) {
+	digest, err := s.digest()
+	if err != nil {
+		return nil, err
+	}
+	return signerAddr(s.KeyType, digest, s.Signature, s.PubKey)
+}
+
+// Validates a channel transaction against the channels in the chain and external
+// transactions (queue or block) that might settle the same channel
+func (bc *Blockchain) validateChannel(t Transaction, external []Transaction) error {
+	if t.Kind == ChannelOpen {
+		if t.Amount == 0 {
+			return invalid(BadChannel, "channel deposit is empty")
+		}
+		if t.Expiry <= bc.height+1 {
+			return invalid(BadChannel, "channel already expired")
+		}
+		return nil
+	}
+
+	c, found := bc.channels[string(t.Channel)]
+	if !found {
+		return invalid(BadChannel, "channel does not exist")
+	}
+	if c.Settled {
+		return invalid(BadChannel, "channel already settled")
+	}
+	for _, trans := range external {
+		if (trans.Kind == ChannelClose || trans.Kind == ChannelRefund) &&
+			trans.Channel.Equals(t.Channel) && !trans.Equals(t) {
+			return invalid(BadChannel, "channel settled twice")
+		}
+	}
+
+	switch t.Kind {
+	case ChannelClose:
+		closerIsParty := t.Sender.Equals(c.Funder) && t.Reciever.Equals(c.Payee) ||
+			t.Sender.Equals(c.Payee) && t.Reciever.Equals(c.Funder)
+		if !closerIsParty {
+			return invalid(BadChannel, "channel closed by outsider")
+		}
+		if t.Amount > c.Deposit {
+			return invalid(BadChannel, "channel deposit is %v, tried to pay %v", c.Deposit, t.Amount)
+		}
+		break
+	case ChannelRefund:
+		if !t.Sender.Equals(c.Funder) || !t.Reciever.Equals(c.Payee) {
+			return invalid(BadChannel, "channel refunded by outsider")
+		}
+		if t.Amount != c.Deposit {
+			return invalid(BadChannel, "channel deposit is %v, tried to refund %v", c.Deposit, t.Amount)
+		}
+		if bc.height+1 < c.Expiry {
+			return invalid(BadChannel, "channel expires at %v", c.Expiry)
+		}
+		break
+	}
+
+	return nil
+}
+
+// Validates the closing state of a channel close was signed by the other party (reciever)
+func validateStateSig(t Transaction) error {
+	state := ChannelState{Channel: t.Channel, Paid: t.Amount, Signature: t.StateSig, KeyType: t.StateKey,
+		PubKey: t.StatePub}
+	signer, err := state.Signer()
+	if err != nil {
+		return invalid(BadChannel, "channel state signature invalid, %v", err)
+	}
+	if !signer.Equals(t.Reciever) {
+		return invalid(BadChannel, "channel state signature invalid")
+	}
+	return nil
+}
+
+// returns the change in balance of addr caused by the transaction
+func (bc *Blockchain) balanceChange(t Transaction, addr Hash) int64 {
+	change := int64(0)
+
+	switch t.Kind {
+	case ChannelOpen:
+		if t.Sender.Equals(addr) {
+			change -= int64(t.Amount)
+		}
+		break
+	case ChannelClose:
+		c, found := bc.channels[string(t.Channel)]
+		if !found {
+			break
+		}
+		if c.Payee.Equals(addr) {
+			change += int64(t.Amount)
+		}
+		if c.Funder.Equals(addr) {
+			change += int64(c.Deposit) - int64(t.Amount)
+		}
+		break
+	case ChannelRefund:
+		if t.Sender.Equals(addr) {
+			change += int64(t.Amount)
+		}
+		break
+	default:
+		if t.Sender.Equals(addr) {
+			change -= int64(t.Amount)
+		} else if t.Reciever.Equals(addr) {
+			change += int64(t.Amount)
+		}
+		break
+	}
+
+	if t.Sender.Equals(addr) {
+		change -= int64(t.Fee) // the fee goes to the miner through the coinbase
+	}
+
+	return change
+}
@@ -0,0 +1,111 @@
+package blockchain
+
+import (
+	"testing"
+)
+
+// returns a chain where funder opened a channel of deposit paying payee
+func openChannel(t *testing.T, funderPriv Hash, funder Hash, payee Hash, deposit uint32) (*Blockchain, Hash) {
+	t.Helper()
+	bc := newTestChain(funder, 100)
+
+	open := NewChannelOpen(funder, payee, deposit, 10)
+	if err := open.Sign(funderPriv); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.AddBlock(nextBlock(t, bc, payee, open)); err != nil {
+		t.Fatal(err)
+	}
+	id, err := open.ID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bc, id
+}
+
+// returns a state of the channel paying paid, signed with priv
+func signedState(t *testing.T, priv Hash, channel Hash, paid uint32) ChannelState {
+	t.Helper()
+	state := ChannelState{Channel: channel, Paid: paid}
+	if err := state.Sign(priv); err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func TestChannelClose(t *testing.T) {
+	funderPriv, funder := newKey(t)
+	payeePriv, payee := newKey(t)
+	bc, id := openChannel(t, funderPriv, funder, payee, 30)
+
+	settle := NewChannelClose(payee, funder, signedState(t, funderPriv, id, 20))
+	if err := settle.Sign(payeePriv); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.AddBlock(nextBlock(t, bc, payee, settle)); err != nil {
+		t.Fatal(err)
+	}
+	if bal := bc.Balance(funder); bal != 100-30+10 {
+		t.Errorf("funder balance is %v, want 80", bal)
+	}
+}
+
+// the funder closes with the state acknowledged by the payee
+func TestChannelCloseByFunder(t *testing.T) {
+	funderPriv, funder := newKey(t)
+	payeePriv, payee := newKey(t)
+	bc, id := openChannel(t, funderPriv, funder, payee, 30)
+
+	settle := NewChannelClose(funder, payee, signedState(t, payeePriv, id, 5))
+	if err := settle.Sign(funderPriv); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.AddBlock(nextBlock(t, bc, payee, settle)); err != nil {
+		t.Fatal(err)
+	}
+	if bal := bc.Balance(funder); bal != 100-30+25 {
+		t.Errorf("funder balance is %v, want 95", bal)
+	}
+}
+
+func TestChannelCloseByOutsider(t *testing.T) {
+	funderPriv, funder := newKey(t)
+	_, payee := newKey(t)
+	outsiderPriv, outsider := newKey(t)
+	bc, id := openChannel(t, funderPriv, funder, payee, 30)
+
+	settle := NewChannelClose(outsider, funder, signedState(t, funderPriv, id, 30))
+	if err := settle.Sign(outsiderPriv); err != nil {
+		t.Fatal(err)
+	}
+	err := bc.AddBlock(nextBlock(t, bc, payee, settle))
+	if reason := reasonOf(t, err); reason != BadChannel {
+		t.Fatalf("close by an outsider rejected as %v, want %v", reason, BadChannel)
+	}
+}
+
+func TestChannelCloseStateSigner(t *testing.T) {
+	funderPriv, funder := newKey(t)
+	payeePriv, payee := newKey(t)
+	bc, id := openChannel(t, funderPriv, funder, payee, 30)
+
+	// the payee can't pay itself with its own state
+	selfSigned := NewChannelClose(payee, funder, signedState(t, payeePriv, id, 30))
+	if err := selfSigned.Sign(payeePriv); err != nil {
+		t.Fatal(err)
+	}
+	err := bc.AddBlock(nextBlock(t, bc, payee, selfSigned))
+	if reason := reasonOf(t, err); reason != BadChannel {
+		t.Fatalf("close signed by the payee rejected as %v, want %v", reason, BadChannel)
+	}
+
+	// nor the funder refund itself with its own state
+	refund := NewChannelClose(funder, payee, signedState(t, funderPriv, id, 0))
+	if err := refund.Sign(funderPriv); err != nil {
+		t.Fatal(err)
+	}
+	err = bc.AddBlock(nextBlock(t, bc, payee, refund))
+	if reason := reasonOf(t, err); reason != BadChannel {
+		t.Fatalf("close signed by the funder rejected as %v, want %v", reason, BadChannel)
+	}
+}
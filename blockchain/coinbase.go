@@ -0,0 +1,73 @@
+package blockchain
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// NewCoinbase generates the reward transaction for the block at height, paying miner
+func NewCoinbase(height uint64, miner Hash) Transaction {
+	return Transaction{Seq: 0, Sender: RootHash(), Reciever: miner, Amount: RewardAmount(),
+		Signature: RootHash(), TXID: CoinbaseTXID(height)}
+}
+
+// CoinbaseTXID returns the TXID every coinbase at height must have, so no two coinbases
+// share an ID. Other transactions can't have TXIDs of this length, so they can't take it
+func CoinbaseTXID(height uint64) Hash {
+	sha := sha3.New256()
+	sha.Write([]byte(fmt.Sprintf("coinbase,%v", height)))
+
+	first := sha.Sum(nil)
+	sha = sha3.New256()
+	sha.Write(first)
+
+	return sha.Sum(nil)
+}
+
+// IsCoinbase returns true if the transaction has the form of a reward (sent from the root hash)
+func (t *Transaction) IsCoinbase() bool {
+	return t.Sender.Equals(RootHash())
+}
+
+// Fees returns the total fee paid by the transactions, which the coinbase claims with the
+// reward
+func Fees(transactions []Transaction) uint64 {
+	total := uint64(0)
+	for _, trans := range transactions {
+		total += uint64(trans.Fee)
+	}
+	return total
+}
+
+// Validates the block has exactly one coinbase, first, with the right TXID, and paying the
+// reward plus the fees of the block
+func validateCoinbase(b *Block) error {
+	if len(b.Transactions) == 0 {
+		return invalid(BadReward, "missing coinbase")
+	}
+
+	for ndx, trans := range b.Transactions[1:] {
+		if trans.IsCoinbase() {
+			return invalid(BadReward, "coinbase at position %v", ndx+1)
+		}
+	}
+
+	reward := b.Transactions[0]
+	if !reward.IsCoinbase() {
+		return invalid(BadReward, "first transaction is not a coinbase")
+	}
+	if reward.Kind != Transfer || reward.Fee != 0 || !reward.Signature.Equals(RootHash()) ||
+		validateUnused(reward) != nil {
+		return invalid(BadReward, "coinbase is malformed")
+	}
+	if !reward.TXID.Equals(CoinbaseTXID(b.Height)) {
+		return invalid(BadReward, "coinbase TXID does not match height")
+	}
+	expected := uint64(RewardAmount()) + Fees(b.Transactions[1:])
+	if uint64(reward.Amount) != expected {
+		return invalid(BadReward, "coinbase pays %v, reward plus fees is %v", reward.Amount, expected)
+	}
+
+	return nil
+}
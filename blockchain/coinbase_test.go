@@ -0,0 +1,107 @@
+package blockchain
+
+import (
+	"testing"
+)
+
+func TestValidateCoinbase(t *testing.T) {
+	priv, addr := newKey(t)
+	_, miner := newKey(t)
+
+	tests := []struct {
+		name  string
+		block func() []Transaction // transactions of the block at height 1
+	}{
+		{"missing", func() []Transaction {
+			return []Transaction{signedTransfer(t, priv, addr, miner, 1), signedTransfer(t, priv, addr, miner, 2)}
+		}},
+		{"twice", func() []Transaction {
+			return []Transaction{NewCoinbase(1, miner), NewCoinbase(1, addr), signedTransfer(t, priv, addr, miner, 1)}
+		}},
+		{"not first", func() []Transaction {
+			return []Transaction{signedTransfer(t, priv, addr, miner, 1), NewCoinbase(1, miner)}
+		}},
+		{"wrong amount", func() []Transaction {
+			reward := NewCoinbase(1, miner)
+			reward.Amount++
+			return []Transaction{reward, signedTransfer(t, priv, addr, miner, 1)}
+		}},
+		{"fees unclaimed", func() []Transaction {
+			trans := NewTransaction(addr, miner, 1)
+			trans.Fee = 3
+			if err := trans.Sign(priv); err != nil {
+				t.Fatal(err)
+			}
+			return []Transaction{NewCoinbase(1, miner), trans}
+		}},
+		{"wrong txid", func() []Transaction {
+			reward := NewCoinbase(1, miner)
+			reward.TXID = CoinbaseTXID(2)
+			return []Transaction{reward, signedTransfer(t, priv, addr, miner, 1)}
+		}},
+		{"signed", func() []Transaction {
+			reward := NewCoinbase(1, miner)
+			reward.Signature = MessageDigest([]byte("not the root hash"))
+			return []Transaction{reward, signedTransfer(t, priv, addr, miner, 1)}
+		}},
+	}
+
+	for _, test := range tests {
+		bc := newTestChain(addr, 100)
+		err := bc.AddBlock(mineBlock(t, bc, test.block()))
+		if err == nil {
+			t.Errorf("%v: block added", test.name)
+			continue
+		}
+		if reason := reasonOf(t, err); reason != BadReward {
+			t.Errorf("%v: rejected as %v, want %v", test.name, reason, BadReward)
+		}
+	}
+}
+
+func TestCoinbaseWithFees(t *testing.T) {
+	priv, addr := newKey(t)
+	_, miner := newKey(t)
+	bc := newTestChain(addr, 100)
+
+	trans := NewTransaction(addr, miner, 10)
+	trans.Fee = 3
+	if err := trans.Sign(priv); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.AddBlock(nextBlock(t, bc, miner, trans)); err != nil {
+		t.Fatal(err)
+	}
+	if bal := bc.Balance(miner); bal != 10+50+3 {
+		t.Errorf("miner balance is %v, want 63", bal)
+	}
+	if bal := bc.Balance(addr); bal != 100-10-3 {
+		t.Errorf("sender balance is %v, want 87", bal)
+	}
+}
+
+// a transaction can't take the TXID of a future coinbase
+func TestCoinbaseTXIDReserved(t *testing.T) {
+	priv, addr := newKey(t)
+	_, miner := newKey(t)
+	bc := newTestChain(addr, 100)
+
+	squatter := NewTransaction(addr, miner, 1)
+	squatter.TXID = CoinbaseTXID(2)
+	if err := squatter.Sign(priv); err != nil {
+		t.Fatal(err)
+	}
+	if reason := reasonOf(t, bc.Enqueue(squatter)); reason != Malformed {
+		t.Errorf("queue rejected as %v, want %v", reason, Malformed)
+	}
+	if err := bc.AddBlock(nextBlock(t, bc, miner, squatter)); err == nil {
+		t.Fatal("block with a coinbase TXID added")
+	}
+
+	if err := bc.AddBlock(nextBlock(t, bc, miner, signedTransfer(t, priv, addr, miner, 1))); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.AddBlock(nextBlock(t, bc, miner, signedTransfer(t, priv, addr, miner, 1))); err != nil {
+		t.Fatalf("coinbase at height 2 rejected, %v", err)
+	}
+}
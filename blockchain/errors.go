@@ -0,0 +1,107 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Reason is the reason code of a block or transaction that failed validation
+type Reason int
+
+const (
+	// Malformed is a block or transaction that couldn't be hashed or decoded
+	Malformed Reason = iota
+	// BadHeight is a block that doesn't extend the top of the chain
+	BadHeight
+	// BadPoW is a block whose hash isn't below its target
+	BadPoW
+	// BadPrevHash is a block whose previous hash isn't the top of the chain
+	BadPrevHash
+	// BadTarget is a block with the wrong target
+	BadTarget
+	// BadMerkleRoot is a block whose merkle root doesn't match its transactions
+	BadMerkleRoot
+	// EmptyBlock is a block without transactions besides the reward
+	EmptyBlock
+	// BadReward is a block with a missing, misplaced or incorrect reward
+	BadReward
+	// BadSequence is a transaction whose sequence number isn't its position in the block
+	BadSequence
+	// DuplicateTXID is a transaction whose TXID or ID was already used
+	DuplicateTXID
+	// InsufficientBalance is a transaction spending more than the sender has
+	InsufficientBalance
+	// BadSignature is a transaction that wasn't signed by the sender
+	BadSignature
+	// SelfTransfer is a transaction whose sender and reciever are the same
+	SelfTransfer
+	// BadChannel is a channel transaction that breaks the channel rules
+	BadChannel
+	// BadSnapshot is a snapshot that doesn't match the trusted snapshot
+	BadSnapshot
+	// BadCheckpoint is a block that conflicts with a checkpoint
+	BadCheckpoint
+)
+
+func (r Reason) String() string {
+	switch r {
+	case Malformed:
+		return "malformed"
+	case BadHeight:
+		return "bad-height"
+	case BadPoW:
+		return "bad-pow"
+	case BadPrevHash:
+		return "bad-prev-hash"
+	case BadTarget:
+		return "bad-target"
+	case BadMerkleRoot:
+		return "bad-merkle-root"
+	case EmptyBlock:
+		return "empty-block"
+	case BadReward:
+		return "bad-reward"
+	case BadSequence:
+		return "bad-sequence"
+	case DuplicateTXID:
+		return "duplicate-txid"
+	case InsufficientBalance:
+		return "insufficient-balance"
+	case BadSignature:
+		return "bad-signature"
+	case SelfTransfer:
+		return "self-transfer"
+	case BadChannel:
+		return "bad-channel"
+	case BadSnapshot:
+		return "bad-snapshot"
+	case BadCheckpoint:
+		return "bad-checkpoint"
+	default:
+		return "undefined"
+	}
+}
+
+// ValidationError is returned when a block, transaction, or snapshot breaks a consensus rule
+type ValidationError struct {
+	Reason Reason
+	Msg    string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Reason, e.Msg)
+}
+
+// returns a validation error with a formatted message
+func invalid(reason Reason, format string, a ...interface{}) error {
+	return &ValidationError{Reason: reason, Msg: fmt.Sprintf(format, a...)}
+}
+
+// ReasonOf returns the reason code of a validation error, false if err isn't one
+func ReasonOf(err error) (Reason, bool) {
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return verr.Reason, true
+	}
+	return Malformed, false
+}
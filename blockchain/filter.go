@@ -0,0 +1,319 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+
+	"golang.org/x/crypto/sha3"
+)
+
+const filterP uint = 19       // golomb-rice parameter (bits of remainder)
+const filterM uint64 = 784931 // inverse false positive rate
+
+// Filter is a golomb-coded set (BIP158 style) of the addresses touched by a block. Light
+// wallets match their addresses against it to find out which blocks they need to download
+type Filter struct {
+	Height    uint64 // height of the filtered block
+	BlockHash Hash   // hash of the filtered block, first 16 bytes are the siphash key
+	N         uint32 // number of addresses in the set
+	Data      Hash   // golomb-rice coded, sorted deltas of the hashed addresses
+	Header    Hash   // filter header, commits to this filter, its block hash and all previous ones
+}
+
+// NewFilter builds the filter for a block (sender and reciever of every transaction).
+// Does not calculate the filter header
+func NewFilter(b *Block) (*Filter, error) {
+	blockHash, err := b.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	// collect unique addresses, the root hash is in every reward so it is skipped
+	seen := make(map[string]struct{})
+	items := make([]Hash, 0, 2*len(b.Transactions))
+	for _, trans := range b.Transactions {
+		for _, addr := range []Hash{trans.Sender, trans.Reciever} {
+			if len(addr) == 0 || addr.Equals(RootHash()) {
+				continue
+			}
+			if _, found := seen[string(addr)]; !found {
+				seen[string(addr)] = struct{}{}
+				items = append(items, addr)
+			}
+		}
+	}
+
+	f := Filter{Height: b.Height, BlockHash: blockHash, N: uint32(len(items))}
+	f.Data = encodeSet(f.hashedSet(items))
+
+	return &f, nil
+}
+
+// returns the items hashed into [0, N*M), sorted
+func (f *Filter) hashedSet(items []Hash) []uint64 {
+	k0, k1 := f.key()
+	nm := uint64(f.N) * filterM
+
+	set := make([]uint64, len(items))
+	for i, item := range items {
+		// (hash * N*M) >> 64 maps uniformly onto the range without a modulo
+		set[i], _ = bits.Mul64(sipHash24(k0, k1, item), nm)
+	}
+	sort.Slice(set, func(i, j int) bool { return set[i] < set[j] })
+
+	return set
+}
+
+// siphash key is the first 16 bytes of the block hash
+func (f *Filter) key() (uint64, uint64) {
+	return binary.LittleEndian.Uint64(f.BlockHash[0:8]), binary.LittleEndian.Uint64(f.BlockHash[8:16])
+}
+
+// golomb-rice encodes deltas between sorted values
+func encodeSet(set []uint64) Hash {
+	w := bitWriter{}
+	last := uint64(0)
+	for _, val := range set {
+		delta := val - last
+		last = val
+
+		for q := delta >> filterP; q > 0; q-- {
+			w.writeBit(1)
+		}
+		w.writeBit(0)
+		w.writeBits(delta, filterP)
+	}
+	return w.bytes()
+}
+
+// Match returns true if the item might be in the set (false positive rate of 1/M)
+func (f *Filter) Match(item Hash) (bool, error) {
+	return f.MatchAny([]Hash{item})
+}
+
+// MatchAny returns true if any item might be in the set
+func (f *Filter) MatchAny(items []Hash) (bool, error) {
+	if f.N == 0 || len(items) == 0 {
+		return false, nil
+	}
+	if len(f.BlockHash) < 16 {
+		return false, errors.New("filter block hash too short")
+	}
+
+	query := f.hashedSet(items)
+	r := bitReader{data: f.Data}
+	val := uint64(0)
+	qi := 0
+
+	// walk both sorted sets, stepping whichever is behind
+	for n := uint32(0); n < f.N; n++ {
+		delta, err := r.readGolomb()
+		if err != nil {
+			return false, err
+		}
+		val += delta
+
+		for qi < len(query) && query[qi] < val {
+			qi++
+		}
+		if qi == len(query) {
+			return false, nil
+		}
+		if query[qi] == val {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Hash double sha3-256 hashes the block hash, the number of items and the coded set. The
+// block hash is the siphash key, so the header chain pins the block each filter was built for
+func (f *Filter) Hash() (Hash, error) {
+	nBuf := new(bytes.Buffer)
+	binary.Write(nBuf, binary.LittleEndian, f.N)
+
+	sha := sha3.New256()
+	if _, err := sha.Write(f.BlockHash); err != nil {
+		return nil, err
+	}
+	if _, err := sha.Write(nBuf.Bytes()); err != nil {
+		return nil, err
+	}
+	if _, err := sha.Write(f.Data); err != nil {
+		return nil, err
+	}
+
+	first := sha.Sum(nil)
+	sha = sha3.New256()
+	if _, err := sha.Write(first); err != nil {
+		return nil, err
+	}
+
+	return sha.Sum(nil), nil
+}
+
+// CalcHeader double sha3-256 hashes the filter hash and the previous filter header.
+// The header before genesis is the root hash
+func (f *Filter) CalcHeader(prev Hash) (Hash, error) {
+	filterHash, err := f.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	sha := sha3.New256()
+	if _, err := sha.Write(filterHash); err != nil {
+		return nil, err
+	}
+	if _, err := sha.Write(prev); err != nil {
+		return nil, err
+	}
+
+	first := sha.Sum(nil)
+	sha = sha3.New256()
+	if _, err := sha.Write(first); err != nil {
+		return nil, err
+	}
+
+	return sha.Sum(nil), nil
+}
+
+func (f *Filter) String() string {
+	return fmt.Sprintf("filter %v: \n\tblock:%v\n\tn:%v\n\theader:%v", f.Height, f.BlockHash, f.N, f.Header)
+}
+
+// bitWriter appends bits most significant first
+type bitWriter struct {
+	buf  []byte
+	cur  byte
+	used uint
+}
+
+func (w *bitWriter) writeBit(bit uint64) {
+	w.cur = w.cur<<1 | byte(bit&1)
+	w.used++
+	if w.used == 8 {
+		w.buf = append(w.buf, w.cur)
+		w.cur = 0
+		w.used = 0
+	}
+}
+
+func (w *bitWriter) writeBits(val uint64, n uint) {
+	for i := n; i > 0; i-- {
+		w.writeBit(val >> (i - 1))
+	}
+}
+
+// returns written bytes, padding the last byte with zeros
+func (w *bitWriter) bytes() Hash {
+	out := w.buf
+	if w.used > 0 {
+		out = append(out, w.cur<<(8-w.used))
+	}
+	return out
+}
+
+// bitReader reads bits most significant first
+type bitReader struct {
+	data []byte
+	pos  uint // bit position
+}
+
+func (r *bitReader) readBit() (uint64, error) {
+	if r.pos >= uint(len(r.data))*8 {
+		return 0, errors.New("filter data ended early")
+	}
+	bit := r.data[r.pos/8] >> (7 - r.pos%8) & 1
+	r.pos++
+	return uint64(bit), nil
+}
+
+func (r *bitReader) readBits(n uint) (uint64, error) {
+	val := uint64(0)
+	for ; n > 0; n-- {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		val = val<<1 | bit
+	}
+	return val, nil
+}
+
+func (r *bitReader) readGolomb() (uint64, error) {
+	q := uint64(0)
+	for {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if bit == 0 {
+			break
+		}
+		q++
+	}
+
+	rem, err := r.readBits(filterP)
+	if err != nil {
+		return 0, err
+	}
+	return q<<filterP | rem, nil
+}
+
+// sipHash24 is siphash-2-4 of data with key (k0, k1)
+func sipHash24(k0, k1 uint64, data []byte) uint64 {
+	v0 := k0 ^ 0x736f6d6570736575
+	v1 := k1 ^ 0x646f72616e646f6d
+	v2 := k0 ^ 0x6c7967656e657261
+	v3 := k1 ^ 0x7465646279746573
+
+	round := func() {
+		v0 += v1
+		v1 = bits.RotateLeft64(v1, 13)
+		v1 ^= v0
+		v0 = bits.RotateLeft64(v0, 32)
+		v2 += v3
+		v3 = bits.RotateLeft64(v3, 16)
+		v3 ^= v2
+		v0 += v3
+		v3 = bits.RotateLeft64(v3, 21)
+		v3 ^= v0
+		v2 += v1
+		v1 = bits.RotateLeft64(v1, 17)
+		v1 ^= v2
+		v2 = bits.RotateLeft64(v2, 32)
+	}
+
+	n := len(data)
+	for len(data) >= 8 {
+		m := binary.LittleEndian.Uint64(data)
+		v3 ^= m
+		round()
+		round()
+		v0 ^= m
+		data = data[8:]
+	}
+
+	// last block holds the remaining bytes and the length in the top byte
+	last := uint64(n) << 56
+	for i, byt := range data {
+		last |= uint64(byt) << (8 * uint(i))
+	}
+	v3 ^= last
+	round()
+	round()
+	v0 ^= last
+
+	v2 ^= 0xff
+	round()
+	round()
+	round()
+	round()
+
+	return v0 ^ v1 ^ v2 ^ v3
+}
@@ -0,0 +1,97 @@
+package blockchain
+
+import (
+	"testing"
+)
+
+// reverses a displayed (big endian) bitcoin hash into its internal byte order
+func reversed(h Hash) Hash {
+	r := make(Hash, len(h))
+	for i := range h {
+		r[i] = h[len(h)-1-i]
+	}
+	return r
+}
+
+// BIP158 basic filter of testnet block 0, which has the coinbase output script as its only
+// item. The filter is "019dfca8", N as a compact size followed by the coded set
+func TestFilterBIP158Vector(t *testing.T) {
+	blockHash := reversed(unhex(t, "000000000933ea01ad0ee984209779baaec3ced90fa3f408719526f8d77f4943"))
+	script := unhex(t, "4104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac")
+
+	f := Filter{BlockHash: blockHash, N: 1}
+	f.Data = encodeSet(f.hashedSet([]Hash{script}))
+	if want := unhex(t, "9dfca8"); !f.Data.Equals(want) {
+		t.Fatalf("filter data %x, want %x", []byte(f.Data), []byte(want))
+	}
+
+	if match, err := f.Match(script); err != nil || !match {
+		t.Fatalf("filter does not match its item (%v)", err)
+	}
+	if match, err := f.Match(Hash("not in the set")); err != nil || match {
+		t.Fatalf("filter matches an item not in the set (%v)", err)
+	}
+}
+
+// siphash-2-4 reference vectors, key 00..0f and message 00..n-1
+func TestSipHash24(t *testing.T) {
+	key := make(Hash, 16)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	k0, k1 := (&Filter{BlockHash: key}).key()
+
+	want := map[int]uint64{
+		0:  0x726fdb47dd0e0e31,
+		1:  0x74f839c593dc67fd,
+		2:  0x0d6c8009d9a94f5a,
+		15: 0xa129ca6149be45e5,
+	}
+	for n, sum := range want {
+		msg := make([]byte, n)
+		for i := range msg {
+			msg[i] = byte(i)
+		}
+		if got := sipHash24(k0, k1, msg); got != sum {
+			t.Errorf("siphash of %v bytes %x, want %x", n, got, sum)
+		}
+	}
+}
+
+func TestFilterMatchAny(t *testing.T) {
+	items := []Hash{Hash("alice"), Hash("bob"), Hash("carol")}
+	f := Filter{BlockHash: MessageDigest([]byte("block")), N: uint32(len(items))}
+	f.Data = encodeSet(f.hashedSet(items))
+
+	for _, item := range items {
+		if match, err := f.Match(item); err != nil || !match {
+			t.Errorf("filter does not match %s (%v)", item, err)
+		}
+	}
+	if match, err := f.MatchAny([]Hash{Hash("dave"), Hash("bob")}); err != nil || !match {
+		t.Errorf("filter does not match any of a set containing bob (%v)", err)
+	}
+	if match, err := (&Filter{BlockHash: f.BlockHash}).Match(Hash("alice")); err != nil || match {
+		t.Errorf("empty filter matches (%v)", err)
+	}
+}
+
+// a filter rekeyed for another block hash must change the header chain
+func TestFilterHeaderCommitsBlockHash(t *testing.T) {
+	f := Filter{BlockHash: MessageDigest([]byte("block")), N: 1}
+	f.Data = encodeSet(f.hashedSet([]Hash{Hash("alice")}))
+	header, err := f.CalcHeader(RootHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rekeyed := f
+	rekeyed.BlockHash = MessageDigest([]byte("other block"))
+	other, err := rekeyed.CalcHeader(RootHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Equals(other) {
+		t.Fatal("filter header does not commit to the block hash")
+	}
+}
@@ -0,0 +1,216 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// ErrNotIndexed is returned by queries on an index that isn't enabled
+var ErrNotIndexed = errors.New("index is not enabled")
+
+// TxLoc is the position of a transaction in the chain
+type TxLoc struct {
+	Height uint64 // height of the block
+	Pos    uint32 // position in the block, the reward is 0
+}
+
+// optional lookup indexes, a nil map is a disabled index
+type index struct {
+	hashes map[string]uint64  // block heights, indexed by block hash
+	ids    map[string]TxLoc   // transaction positions, indexed by transaction ID
+	addrs  map[string][]TxLoc // positions of the transactions sending to or from an address
+}
+
+// creates the indexes enabled in _I32COIN_INDEXES, a comma separated list of "hash",
+// "txid" (transaction IDs), and "addr"
+func newIndex() *index {
+	idx := index{}
+	for _, name := range strings.Split(os.Getenv("_I32COIN_INDEXES"), ",") {
+		switch strings.TrimSpace(name) {
+		case "hash":
+			idx.hashes = make(map[string]uint64)
+			break
+		case "txid":
+			idx.ids = make(map[string]TxLoc)
+			break
+		case "addr":
+			idx.addrs = make(map[string][]TxLoc)
+			break
+		case "":
+			break
+		default:
+			log.Fatal("blockchain fatal: unknown index ", name)
+		}
+	}
+	return &idx
+}
+
+// indexes a block added to the top of the chain
+func (idx *index) add(b *Block) {
+	if idx.hashes != nil {
+		hash, err := b.Hash()
+		if err != nil {
+			log.Fatal("blockchain fatal: failed to index block: ", err)
+		}
+		idx.hashes[string(hash)] = b.Height
+	}
+
+	for ndx, trans := range b.Transactions {
+		loc := TxLoc{Height: b.Height, Pos: uint32(ndx)}
+		if idx.ids != nil {
+			idx.ids[string(txID(trans))] = loc
+		}
+		if idx.addrs != nil {
+			if !trans.IsCoinbase() {
+				idx.addrs[string(trans.Sender)] = append(idx.addrs[string(trans.Sender)], loc)
+			}
+			if !trans.Reciever.Equals(trans.Sender) {
+				idx.addrs[string(trans.Reciever)] = append(idx.addrs[string(trans.Reciever)], loc)
+			}
+		}
+	}
+}
+
+// returns the ID of an indexed transaction. The chain only holds transactions that hash
+func txID(t Transaction) Hash {
+	id, err := t.ID()
+	if err != nil {
+		log.Fatal("blockchain fatal: failed to index transaction: ", err)
+	}
+	return id
+}
+
+// removes a block from the indexes, blocks must be removed from the top down. The
+// transactions of a pruned block are found by scanning the indexes
+func (idx *index) remove(b *Block) {
+	if idx.hashes != nil {
+		hash, err := b.Hash()
+		if err != nil {
+			log.Fatal("blockchain fatal: failed to unindex block: ", err)
+		}
+		delete(idx.hashes, string(hash))
+	}
+
+	if b.Transactions == nil {
+		idx.removeScan(b.Height)
+		return
+	}
+
+	for _, trans := range b.Transactions {
+		if idx.ids != nil {
+			delete(idx.ids, string(txID(trans)))
+		}
+		if idx.addrs != nil {
+			idx.truncate(string(trans.Sender), b.Height)
+			idx.truncate(string(trans.Reciever), b.Height)
+		}
+	}
+}
+
+// removes the transactions at height from the indexes without the block's body
+func (idx *index) removeScan(height uint64) {
+	for id, loc := range idx.ids {
+		if loc.Height == height {
+			delete(idx.ids, id)
+		}
+	}
+	for addr := range idx.addrs {
+		idx.truncate(addr, height)
+	}
+}
+
+// drops the positions at or above height from the end of an address' transactions
+func (idx *index) truncate(addr string, height uint64) {
+	locs, found := idx.addrs[addr]
+	if !found {
+		return
+	}
+
+	end := len(locs)
+	for end > 0 && locs[end-1].Height >= height {
+		end--
+	}
+	if end == 0 {
+		delete(idx.addrs, addr)
+	} else {
+		idx.addrs[addr] = locs[:end]
+	}
+}
+
+// BlockByHash returns the block with the hash, which is only a header if pruned.
+// Requires the "hash" index
+func (bc *Blockchain) BlockByHash(hash Hash) (*Block, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if bc.index.hashes == nil {
+		return nil, ErrNotIndexed
+	}
+
+	height, found := bc.index.hashes[string(hash)]
+	if !found {
+		return nil, fmt.Errorf("no block with hash %v", hash)
+	}
+	return copyBlock(bc.blocks[height]), nil
+}
+
+// TransactionByID returns the transaction with the ID and its position in the chain.
+// Requires the "txid" index
+func (bc *Blockchain) TransactionByID(id Hash) (*Transaction, TxLoc, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if bc.index.ids == nil {
+		return nil, TxLoc{}, ErrNotIndexed
+	}
+
+	loc, found := bc.index.ids[string(id)]
+	if !found {
+		return nil, TxLoc{}, fmt.Errorf("no transaction with ID %v", id)
+	}
+	t, err := bc.transactionAt(loc)
+	return t, loc, err
+}
+
+// AddressTransactions returns the positions of the transactions sending to or from an
+// address, oldest first. Requires the "addr" index
+func (bc *Blockchain) AddressTransactions(addr Hash) ([]TxLoc, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if bc.index.addrs == nil {
+		return nil, ErrNotIndexed
+	}
+
+	locs := bc.index.addrs[string(addr)]
+	cpy := make([]TxLoc, len(locs))
+	copy(cpy, locs)
+	return cpy, nil
+}
+
+// TransactionAt returns the transaction at a position in the chain
+func (bc *Blockchain) TransactionAt(loc TxLoc) (*Transaction, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.transactionAt(loc)
+}
+
+// transactionAt is TransactionAt without locking, must hold the lock
+func (bc *Blockchain) transactionAt(loc TxLoc) (*Transaction, error) {
+	b, found := bc.blocks[loc.Height]
+	if !found || loc.Height > bc.height {
+		return nil, fmt.Errorf("no block at height %v", loc.Height)
+	}
+	if b.Transactions == nil {
+		return nil, fmt.Errorf("block %v is pruned", loc.Height)
+	}
+	if int(loc.Pos) >= len(b.Transactions) {
+		return nil, fmt.Errorf("no transaction at position %v of block %v", loc.Pos, loc.Height)
+	}
+
+	t := b.Transactions[loc.Pos]
+	return &t, nil
+}
@@ -0,0 +1,40 @@
+package blockchain
+
+import (
+	"os"
+	"testing"
+)
+
+func TestTransactionByID(t *testing.T) {
+	os.Setenv("_I32COIN_INDEXES", "txid")
+	defer os.Unsetenv("_I32COIN_INDEXES")
+
+	priv, addr := newKey(t)
+	_, miner := newKey(t)
+	bc := newTestChain(addr, 100)
+
+	trans := signedTransfer(t, priv, addr, miner, 10)
+	if err := bc.AddBlock(nextBlock(t, bc, miner, trans)); err != nil {
+		t.Fatal(err)
+	}
+	id, err := trans.ID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found, loc, err := bc.TransactionByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found.Equals(trans) || loc != (TxLoc{Height: 1, Pos: 1}) {
+		t.Errorf("found %v at %v, want the transfer at 1:1", found, loc)
+	}
+	if _, _, err := bc.TransactionByID(trans.TXID); err == nil {
+		t.Error("transaction found by its random TXID")
+	}
+
+	bc.removeBlocks(1)
+	if _, _, err := bc.TransactionByID(id); err == nil {
+		t.Error("transaction of a removed block found")
+	}
+}
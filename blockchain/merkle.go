@@ -1,6 +1,8 @@
 package blockchain
 
 import (
+	"encoding/binary"
+
 	"github.com/cbergoon/merkletree"
 	"golang.org/x/crypto/sha3"
 )
@@ -10,9 +12,33 @@ type merkleLeaf struct {
 	trans Transaction
 }
 
-// CalculateHash is required by merkletree.Content interface
+// CalculateHash is required by merkletree.Content interface. Leaves double sha3-256 hash the
+// transaction ID and the signatures it leaves out (transaction and channel state), each
+// prefixed with its length, so a block commits to the exact signatures it was mined with
 func (l merkleLeaf) CalculateHash() ([]byte, error) {
-	return l.trans.hash()
+	id, err := l.trans.ID()
+	if err != nil {
+		return nil, err
+	}
+
+	sha := sha3.New256()
+	for _, field := range []Hash{id, l.trans.Signature, l.trans.StateSig, {byte(l.trans.StateKey)},
+		l.trans.StatePub} {
+		if err := binary.Write(sha, binary.LittleEndian, uint32(len(field))); err != nil {
+			return nil, err
+		}
+		if _, err := sha.Write(field); err != nil {
+			return nil, err
+		}
+	}
+
+	first := sha.Sum(nil)
+	sha = sha3.New256()
+	if _, err := sha.Write(first); err != nil {
+		return nil, err
+	}
+
+	return sha.Sum(nil), nil
 }
 
 // Equals is required by merkletree.Content interface
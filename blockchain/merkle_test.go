@@ -0,0 +1,50 @@
+package blockchain
+
+import (
+	"testing"
+)
+
+// leaves commit to the signatures, which the transaction ID leaves out
+func TestMerkleRootCommitsSignatures(t *testing.T) {
+	funderPriv, funder := newKey(t)
+	payeePriv, payee := newKey(t)
+
+	settle := NewChannelClose(payee, funder, signedState(t, funderPriv, Hash("channel"), 1))
+	if err := settle.Sign(payeePriv); err != nil {
+		t.Fatal(err)
+	}
+	transactions := []Transaction{NewCoinbase(1, payee), settle}
+	root, err := CalcMerkleRoot(transactions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// none of these change the ID
+	mutations := map[string]func(trans *Transaction){
+		"signature": func(trans *Transaction) {
+			trans.Signature = append(Hash{}, trans.Signature...)
+			trans.Signature[0] ^= 1
+		},
+		"state signature": func(trans *Transaction) {
+			trans.StateSig = append(Hash{}, trans.StateSig...)
+			trans.StateSig[0] ^= 1
+		},
+		"state key type":   func(trans *Transaction) { trans.StateKey = Schnorr },
+		"state public key": func(trans *Transaction) { trans.StatePub = Hash("key") },
+	}
+	for name, mutate := range mutations {
+		mutated := append([]Transaction{}, transactions...)
+		mutate(&mutated[1])
+
+		if !mutated[1].Equals(transactions[1]) {
+			t.Fatalf("%v: mutation changed the transaction ID", name)
+		}
+		mutatedRoot, err := CalcMerkleRoot(mutated)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mutatedRoot.Equals(root) {
+			t.Errorf("%v is not committed to by the merkle root", name)
+		}
+	}
+}
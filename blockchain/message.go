@@ -0,0 +1,98 @@
+package blockchain
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// prefix of signed messages. Transaction digests start with the sender's hex address, so a
+// message signature can never be a transaction signature
+const messagePrefix = "\x19int32coin signed message:\n"
+
+// MessageSignature proves the owner of an address signed a message
+type MessageSignature struct {
+	KeyType   KeyType // signature scheme of the signer's key
+	Signature Hash
+	PubKey    Hash // public key of the signer, for schemes that can't recover it
+}
+
+// MessageDigest returns the digest signed for a message, the double sha3-256 of the prefixed,
+// length tagged message
+func MessageDigest(msg []byte) Hash {
+	sha := sha3.New256()
+	sha.Write([]byte(fmt.Sprintf("%v%v:", messagePrefix, len(msg))))
+	sha.Write(msg)
+
+	first := sha.Sum(nil)
+	sha = sha3.New256()
+	sha.Write(first)
+
+	return sha.Sum(nil)
+}
+
+// SignMessage signs a message with a private key of the given type
+func SignMessage(msg []byte, kt KeyType, priv Hash) (*MessageSignature, error) {
+	scheme, err := Scheme(kt)
+	if err != nil {
+		return nil, err
+	}
+
+	s := MessageSignature{KeyType: kt}
+	if kt != ECDSA {
+		if s.PubKey, err = scheme.Public(priv); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.Signature, err = scheme.Sign(MessageDigest(msg), priv); err != nil {
+		return nil, err
+	}
+	if err := scheme.Canonical(s.Signature); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// VerifyMessage validates the message was signed by the key of addr
+func (s *MessageSignature) VerifyMessage(msg []byte, addr Hash) error {
+	signer, err := signerAddr(s.KeyType, MessageDigest(msg), s.Signature, s.PubKey)
+	if err != nil {
+		return err
+	}
+	if !signer.Equals(addr) {
+		return errors.New("message was not signed by the address")
+	}
+	return nil
+}
+
+// String encodes the signature as base64 of its key type, signature length, signature and
+// public key
+func (s *MessageSignature) String() string {
+	buf := make([]byte, 0, 2+len(s.Signature)+len(s.PubKey))
+	buf = append(buf, byte(s.KeyType), byte(len(s.Signature)))
+	buf = append(buf, s.Signature...)
+	buf = append(buf, s.PubKey...)
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// ParseMessageSignature decodes a signature encoded by String
+func ParseMessageSignature(str string) (*MessageSignature, error) {
+	buf, err := base64.StdEncoding.DecodeString(str)
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) < 2 || len(buf) < 2+int(buf[1]) {
+		return nil, errors.New("message signature is malformed")
+	}
+
+	sigEnd := 2 + int(buf[1])
+	s := MessageSignature{KeyType: KeyType(buf[0]), Signature: buf[2:sigEnd]}
+	if sigEnd < len(buf) {
+		s.PubKey = buf[sigEnd:]
+	}
+	return &s, nil
+}
@@ -0,0 +1,87 @@
+package blockchain
+
+import "testing"
+
+// returns a private key of the key type and its address
+func newSchemeKey(t *testing.T, kt KeyType) (Hash, Hash) {
+	t.Helper()
+	scheme, err := Scheme(kt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, pub, err := scheme.NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv, Address(kt, pub)
+}
+
+func TestMessageSignVerify(t *testing.T) {
+	msg := []byte("log in to example.com\nnonce 7f3a, any bytes \x00\xff")
+	for _, kt := range []KeyType{ECDSA, Ed25519, Schnorr} {
+		priv, addr := newSchemeKey(t, kt)
+		_, other := newSchemeKey(t, kt)
+
+		sig, err := SignMessage(msg, kt, priv)
+		if err != nil {
+			t.Fatalf("%v: %v", kt, err)
+		}
+		parsed, err := ParseMessageSignature(sig.String())
+		if err != nil {
+			t.Fatalf("%v: %v", kt, err)
+		}
+		if err := parsed.VerifyMessage(msg, addr); err != nil {
+			t.Errorf("%v: signature rejected, %v", kt, err)
+		}
+		if err := parsed.VerifyMessage(append(msg, '.'), addr); err == nil {
+			t.Errorf("%v: signature verified another message", kt)
+		}
+		if err := parsed.VerifyMessage(msg, other); err == nil {
+			t.Errorf("%v: signature verified for another address", kt)
+		}
+	}
+}
+
+func TestParseMessageSignatureMalformed(t *testing.T) {
+	for _, str := range []string{"", "not base64!", "AA==", "AEA="} {
+		if _, err := ParseMessageSignature(str); err == nil {
+			t.Errorf("%q parsed", str)
+		}
+	}
+}
+
+// a message signature can't be used as a transaction signature, even over the bytes the
+// transaction digest hashes, and a transaction signature doesn't verify as a message
+func TestMessageSignatureNotTransaction(t *testing.T) {
+	for _, kt := range []KeyType{ECDSA, Ed25519, Schnorr} {
+		priv, addr := newSchemeKey(t, kt)
+		_, other := newSchemeKey(t, kt)
+
+		trans := NewTransaction(addr, other, 10)
+		trans.KeyType = kt
+		if err := trans.Sign(priv); err != nil {
+			t.Fatalf("%v: %v", kt, err)
+		}
+		id, err := trans.ID()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, msg := range [][]byte{trans.predigest(), id} {
+			sig, err := SignMessage(msg, kt, priv)
+			if err != nil {
+				t.Fatalf("%v: %v", kt, err)
+			}
+			forged := trans
+			forged.Signature = sig.Signature
+			if reason := reasonOf(t, forged.ValidateSignature()); reason != BadSignature {
+				t.Errorf("%v: message signature used for a transaction, rejected as %v", kt, reason)
+			}
+
+			transSig := MessageSignature{KeyType: kt, Signature: trans.Signature, PubKey: trans.PubKey}
+			if err := transSig.VerifyMessage(msg, addr); err == nil {
+				t.Errorf("%v: transaction signature verified as a message", kt)
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// parses a "height:hash" chain parameter
+func parseHeightHash(param string) (uint64, Hash) {
+	parts := strings.Split(strings.TrimSpace(param), ":")
+	if len(parts) != 2 {
+		log.Fatal("blockchain fatal: malformed height and hash, ", param)
+	}
+
+	height, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		log.Fatal("blockchain fatal: malformed height, ", parts[0])
+	}
+	hash, err := hex.DecodeString(parts[1])
+	if err != nil || len(hash) != shaHashSize {
+		log.Fatal("blockchain fatal: malformed hash, ", parts[1])
+	}
+
+	return height, hash
+}
+
+var (
+	checkpoints  map[uint64]Hash // hashes of the blocks every chain must contain, by height
+	assumeHeight uint64          // height of the assume-valid block
+	assumeHash   Hash            // hash of the assume-valid block, nil if none is configured
+)
+
+// parses the checkpoint and assume-valid parameters, when a chain is created
+func loadParams() {
+	checkpoints = make(map[uint64]Hash)
+	if val := os.Getenv("_I32COIN_CHECKPOINTS"); val != "" {
+		for _, param := range strings.Split(val, ",") {
+			height, hash := parseHeightHash(param)
+			checkpoints[height] = hash
+		}
+	}
+
+	assumeHeight, assumeHash = 0, nil
+	if val := os.Getenv("_I32COIN_ASSUME_VALID"); val != "" {
+		assumeHeight, assumeHash = parseHeightHash(val)
+	}
+}
+
+// Checkpoints returns the hashes of the blocks every chain must contain, indexed by height
+func Checkpoints() map[uint64]Hash {
+	return checkpoints
+}
+
+// LastCheckpoint returns the height of the highest checkpoint at or below height, 0 if
+// there is none. Blocks at or below it can't be removed
+func LastCheckpoint(height uint64) uint64 {
+	last := uint64(0)
+	for h := range checkpoints {
+		if h <= height && h > last {
+			last = h
+		}
+	}
+	return last
+}
+
+// AssumeValid returns the height and hash of the block whose ancestors' signatures are
+// assumed valid, false if none is configured
+func AssumeValid() (uint64, Hash, bool) {
+	return assumeHeight, assumeHash, assumeHash != nil
+}
@@ -0,0 +1,64 @@
+package blockchain
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+)
+
+// PruneDepth returns the number of most recent block bodies kept, 0 if pruning is off
+func PruneDepth() uint64 {
+	val := os.Getenv("_I32COIN_PRUNE_DEPTH")
+	if val == "" {
+		return 0
+	}
+
+	depth, err := strconv.ParseUint(val, 10, 64)
+	if err != nil {
+		log.Fatal("blockchain fatal: could not determine prune depth")
+	}
+	return depth
+}
+
+// LowestBody returns the lowest height whose block body is kept by a chain of the given
+// height. Blocks below it only have their headers
+func LowestBody(height uint64) uint64 {
+	depth := PruneDepth()
+	if depth == 0 || height < depth {
+		return 0
+	}
+	return height - depth + 1
+}
+
+// replaces the blocks that fell below the prune depth with their headers. Headers and
+// filters are kept, the account state doesn't need the bodies
+func (bc *Blockchain) prune() {
+	lowest := LowestBody(bc.height)
+	if len(bc.assumed) > 0 && bc.assumed[0] < lowest {
+		lowest = bc.assumed[0] // keep bodies whose signatures may still be verified
+	}
+	for h := lowest; h > bc.base; h-- {
+		b := bc.blocks[h-1]
+		if b.Transactions == nil {
+			break // already pruned below
+		}
+
+		header := *b // copy, the block may still be referenced elsewhere
+		header.Transactions = nil
+		bc.blocks[h-1] = &header
+		log.Println("blockchain: pruned block ", h-1)
+	}
+}
+
+// returns an error if the block bodies from first to the top have been pruned
+func (bc *Blockchain) checkServed(first uint64) error {
+	lowest := LowestBody(bc.height)
+	if bc.base > 0 && lowest <= bc.base {
+		lowest = bc.base + 1 // only the header of the snapshot block is known
+	}
+	if first < lowest {
+		return fmt.Errorf("blocks below %v are pruned, requested %v", lowest, first)
+	}
+	return nil
+}
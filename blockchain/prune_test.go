@@ -0,0 +1,118 @@
+package blockchain
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+// keeps depth block bodies in the chains created until the test ends
+func setPruneDepth(t *testing.T, depth uint64) {
+	t.Helper()
+	os.Setenv("_I32COIN_PRUNE_DEPTH", fmt.Sprint(depth))
+	t.Cleanup(func() { os.Unsetenv("_I32COIN_PRUNE_DEPTH") })
+}
+
+// returns a chain of 5 blocks keeping 2 bodies, the hashes of its blocks and the balances of
+// sender and miner after block 1
+func prunedChain(t *testing.T) (*Blockchain, []Hash, Hash, Hash, [2]int64) {
+	t.Helper()
+	setPruneDepth(t, 2)
+	priv, addr := newKey(t)
+	_, miner := newKey(t)
+	bc := newTestChain(addr, 100)
+
+	hash, err := bc.top().Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes := []Hash{hash}
+	var balances [2]int64
+	for h := 1; h <= 5; h++ {
+		b := nextBlock(t, bc, miner, signedTransfer(t, priv, addr, miner, uint32(h)))
+		if err := bc.AddBlock(b); err != nil {
+			t.Fatal(err)
+		}
+		if hash, err = b.Hash(); err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, hash)
+		if h == 1 {
+			balances = [2]int64{bc.Balance(addr), bc.Balance(miner)}
+		}
+	}
+	return bc, hashes, addr, miner, balances
+}
+
+// pruned blocks keep their headers, so their hashes and the links between them don't change
+func TestPruneKeepsHeaders(t *testing.T) {
+	bc, hashes, _, _, _ := prunedChain(t)
+
+	for h := uint64(0); h <= bc.height; h++ {
+		b := bc.blocks[h]
+		if pruned := b.Transactions == nil; pruned != (h < LowestBody(bc.height)) {
+			t.Errorf("block %v pruned is %v, lowest body is %v", h, pruned, LowestBody(bc.height))
+		}
+		hash, err := b.Hash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hash.Equals(hashes[h]) {
+			t.Errorf("block %v hash changed after pruning", h)
+		}
+		if h > 0 && !b.PrevHash.Equals(hashes[h-1]) {
+			t.Errorf("block %v no longer links to its parent", h)
+		}
+	}
+}
+
+func TestGetRangePruned(t *testing.T) {
+	bc, _, _, _, _ := prunedChain(t)
+	lowest := LowestBody(bc.height)
+
+	for _, first := range []uint64{0, 1, lowest - 1} {
+		if blocks, err := bc.getRange(first); err == nil {
+			t.Errorf("range from pruned height %v served %v blocks", first, len(blocks))
+		}
+	}
+
+	blocks, err := bc.getRange(lowest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uint64(len(blocks)) != bc.height-lowest+1 {
+		t.Fatalf("range from %v has %v blocks, want %v", lowest, len(blocks), bc.height-lowest+1)
+	}
+	for _, b := range blocks {
+		if b.Transactions == nil {
+			t.Errorf("range served the pruned block %v", b.Height)
+		}
+	}
+}
+
+// reverting uses the undo data, not the pruned bodies
+func TestRemoveBlocksPruned(t *testing.T) {
+	bc, hashes, addr, miner, balances := prunedChain(t)
+	if bc.blocks[2].Transactions != nil {
+		t.Fatal("block 2 is not pruned")
+	}
+
+	bc.removeBlocks(2)
+	if bc.height != 1 {
+		t.Fatalf("height %v after removing, want 1", bc.height)
+	}
+	if bal := bc.Balance(addr); bal != balances[0] {
+		t.Errorf("sender balance %v after removing, want %v", bal, balances[0])
+	}
+	if bal := bc.Balance(miner); bal != balances[1] {
+		t.Errorf("miner balance %v after removing, want %v", bal, balances[1])
+	}
+
+	top, err := bc.top().Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !top.Equals(hashes[1]) {
+		t.Error("top isn't block 1 after removing")
+	}
+}
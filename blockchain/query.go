@@ -0,0 +1,83 @@
+package blockchain
+
+import (
+	"fmt"
+)
+
+// Reader is the read-only view of the block chain, safe to use from any goroutine.
+// Returned blocks and transactions are copies
+type Reader interface {
+	Height() uint64
+	Top() *Block
+	BlockAt(height uint64) (*Block, error)
+	BlockByHash(hash Hash) (*Block, error)
+	TransactionByID(id Hash) (*Transaction, TxLoc, error)
+	TransactionAt(loc TxLoc) (*Transaction, error)
+	AddressTransactions(addr Hash) ([]TxLoc, error)
+	Balance(addr Hash) int64
+	BalanceChange(t Transaction, addr Hash) int64
+	Mempool() []Transaction
+}
+
+var _ Reader = (*Blockchain)(nil)
+
+// copies a block and its transaction slice, so the copy stays the same when the chain changes
+func copyBlock(b *Block) *Block {
+	cpy := *b
+	if b.Transactions != nil {
+		cpy.Transactions = make([]Transaction, len(b.Transactions))
+		copy(cpy.Transactions, b.Transactions)
+	}
+	return &cpy
+}
+
+// Height returns the height of the top of the chain
+func (bc *Blockchain) Height() uint64 {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.height
+}
+
+// Top returns a copy of the top of the chain
+func (bc *Blockchain) Top() *Block {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return copyBlock(bc.top())
+}
+
+// BlockAt returns a copy of the block at height, which is only a header if pruned
+func (bc *Blockchain) BlockAt(height uint64) (*Block, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	b, found := bc.blocks[height]
+	if !found || height > bc.height {
+		return nil, fmt.Errorf("no block at height %v", height)
+	}
+	return copyBlock(b), nil
+}
+
+// Balance returns the balance of an address at the top of the chain
+func (bc *Blockchain) Balance(addr Hash) int64 {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.balances[string(addr)]
+}
+
+// BalanceChange returns the change in balance of addr the transaction causes at the top of
+// the chain
+func (bc *Blockchain) BalanceChange(t Transaction, addr Hash) int64 {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.balanceChange(t, addr)
+}
+
+// Mempool returns a copy of the queued transactions, not yet in any block
+func (bc *Blockchain) Mempool() []Transaction {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	queued := make([]Transaction, len(bc.queued))
+	copy(queued, bc.queued)
+	return queued
+}
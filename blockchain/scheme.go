@@ -0,0 +1,248 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/secp256k1"
+	"golang.org/x/crypto/sha3"
+)
+
+// KeyType tags the signature scheme of a key, and of the addresses and transactions made with it
+type KeyType uint8
+
+const (
+	// ECDSA is secp256k1 with recoverable signatures, the original scheme
+	ECDSA KeyType = iota
+	// Ed25519 is edwards curve 25519
+	Ed25519
+	// Schnorr is BIP340 schnorr over secp256k1
+	Schnorr
+)
+
+func (kt KeyType) String() string {
+	switch kt {
+	case ECDSA:
+		return "ecdsa"
+	case Ed25519:
+		return "ed25519"
+	case Schnorr:
+		return "schnorr"
+	default:
+		return "undefined"
+	}
+}
+
+// SigScheme is a signature algorithm for signing digests
+type SigScheme interface {
+	// NewKey generates a private and public key pair
+	NewKey() (Hash, Hash, error)
+	// Public returns the public key of a private key
+	Public(priv Hash) (Hash, error)
+	// CheckPublic returns an error if pub isn't a public key of the scheme
+	CheckPublic(pub Hash) error
+	// Sign generates a signature of the digest
+	Sign(digest Hash, priv Hash) (Hash, error)
+	// Verify validates the signature of the digest and returns the signer's public key.
+	// Schemes that recover the key from the signature ignore pub. Only canonical
+	// signatures are valid
+	Verify(digest Hash, sig Hash, pub Hash) (Hash, error)
+	// Canonical returns an error if the signature isn't in the scheme's single canonical
+	// encoding, so it can't be altered by third parties and stay valid
+	Canonical(sig Hash) error
+}
+
+var secp256k1HalfN = new(big.Int).Rsh(curve.N, 1)
+
+// order of the ed25519 base point, 2^252 + 27742317777372353535851937790883648493
+var ed25519Order, _ = new(big.Int).SetString("1000000000000000000000000000000014def9dea2f79cd65812631a5cf5d3ed", 16)
+
+var schemes = map[KeyType]SigScheme{
+	ECDSA:   ecdsaScheme{},
+	Ed25519: ed25519Scheme{},
+	Schnorr: schnorrScheme{},
+}
+
+// Scheme returns the signature scheme of the key type
+func Scheme(kt KeyType) (SigScheme, error) {
+	scheme, found := schemes[kt]
+	if !found {
+		return nil, fmt.Errorf("unknown key type %v", uint8(kt))
+	}
+	return scheme, nil
+}
+
+// Address derives the address of a public key, sha3-256 of the key. Other schemes than
+// ECDSA prefix the key with their type tag, so an address commits to its key type
+func Address(kt KeyType, pub Hash) Hash {
+	sha := sha3.New256()
+	if kt != ECDSA {
+		sha.Write([]byte{byte(kt)})
+	}
+	sha.Write(pub)
+	return Hash(sha.Sum(nil))
+}
+
+// returns the address of the key that signed the digest
+func signerAddr(kt KeyType, digest Hash, sig Hash, pub Hash) (Hash, error) {
+	key := sigKey(kt, digest, sig, pub)
+	if addr, found := verified.get(key); found {
+		return addr, nil
+	}
+
+	scheme, err := Scheme(kt)
+	if err != nil {
+		return nil, err
+	}
+
+	sigpub, err := scheme.Verify(digest, sig, pub)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := Address(kt, sigpub)
+	verified.add(key, addr)
+	return addr, nil
+}
+
+// ecdsaScheme is secp256k1 ecdsa with 65 byte recoverable signatures
+type ecdsaScheme struct{}
+
+func (ecdsaScheme) NewKey() (Hash, Hash, error) {
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	pub, ok := priv.Public().(*ecdsa.PublicKey)
+	if !ok {
+		return nil, nil, errors.New("cast to public key failed")
+	}
+	return Hash(crypto.FromECDSA(priv)), Hash(crypto.FromECDSAPub(pub)), nil
+}
+
+func (ecdsaScheme) Public(priv Hash) (Hash, error) {
+	key, err := crypto.ToECDSA(priv)
+	if err != nil {
+		return nil, err
+	}
+	return Hash(crypto.FromECDSAPub(&key.PublicKey)), nil
+}
+
+// CheckPublic requires an uncompressed point on the curve
+func (ecdsaScheme) CheckPublic(pub Hash) error {
+	_, err := crypto.UnmarshalPubkey(pub)
+	return err
+}
+
+func (ecdsaScheme) Sign(digest Hash, priv Hash) (Hash, error) {
+	sig, err := secp256k1.Sign(digest, priv)
+	if err != nil {
+		return nil, err
+	}
+
+	// normalize to low s, (r, n-s) is also valid for the flipped recovery id
+	s := new(big.Int).SetBytes(sig[32:64])
+	if s.Cmp(secp256k1HalfN) > 0 {
+		copy(sig[32:64], Pad32(s.Sub(curve.N, s)))
+		sig[64] ^= 1
+	}
+
+	return Hash(sig), nil
+}
+
+// Canonical requires 65 bytes (r, s, recovery id) with s in the lower half of the order
+func (ecdsaScheme) Canonical(sig Hash) error {
+	if len(sig) != crypto.SignatureLength {
+		return errors.New("invalid ecdsa signature length")
+	}
+
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:64])
+	if !crypto.ValidateSignatureValues(sig[64], r, s, true) {
+		return errors.New("signature not canonical")
+	}
+
+	return nil
+}
+
+func (scheme ecdsaScheme) Verify(digest Hash, sig Hash, pub Hash) (Hash, error) {
+	if err := scheme.Canonical(sig); err != nil {
+		return nil, err
+	}
+
+	// get public key of signature
+	sigpub, err := crypto.SigToPub(digest, sig)
+	if err != nil {
+		return nil, err
+	}
+	return Hash(crypto.FromECDSAPub(sigpub)), nil
+}
+
+// ed25519Scheme is ed25519, the private key is the 32 byte seed
+type ed25519Scheme struct{}
+
+func (ed25519Scheme) NewKey() (Hash, Hash, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	return Hash(priv.Seed()), Hash(pub), nil
+}
+
+func (ed25519Scheme) Public(priv Hash) (Hash, error) {
+	if len(priv) != ed25519.SeedSize {
+		return nil, errors.New("invalid ed25519 private key length")
+	}
+	key := ed25519.NewKeyFromSeed(priv)
+	return Hash(key.Public().(ed25519.PublicKey)), nil
+}
+
+func (ed25519Scheme) CheckPublic(pub Hash) error {
+	if len(pub) != ed25519.PublicKeySize {
+		return errors.New("invalid ed25519 public key length")
+	}
+	return nil
+}
+
+func (ed25519Scheme) Sign(digest Hash, priv Hash) (Hash, error) {
+	if len(priv) != ed25519.SeedSize {
+		return nil, errors.New("invalid ed25519 private key length")
+	}
+	return Hash(ed25519.Sign(ed25519.NewKeyFromSeed(priv), digest)), nil
+}
+
+// Canonical requires 64 bytes (R, S) with S reduced below the group order
+func (ed25519Scheme) Canonical(sig Hash) error {
+	if len(sig) != ed25519.SignatureSize {
+		return errors.New("invalid ed25519 signature length")
+	}
+
+	// S is little endian
+	s := make([]byte, 32)
+	for i := range s {
+		s[i] = sig[63-i]
+	}
+	if new(big.Int).SetBytes(s).Cmp(ed25519Order) >= 0 {
+		return errors.New("signature not canonical")
+	}
+
+	return nil
+}
+
+func (scheme ed25519Scheme) Verify(digest Hash, sig Hash, pub Hash) (Hash, error) {
+	if err := scheme.Canonical(sig); err != nil {
+		return nil, err
+	}
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid ed25519 public key length")
+	}
+	if !ed25519.Verify(ed25519.PublicKey(pub), digest, sig) {
+		return nil, errors.New("signature invalid")
+	}
+	return pub, nil
+}
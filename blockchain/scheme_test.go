@@ -0,0 +1,246 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+func unhex(t *testing.T, s string) Hash {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Hash(b)
+}
+
+// verification vectors from BIP340's test-vectors.csv
+var schnorrVectors = []struct {
+	priv, pub, msg, sig string
+	valid               bool
+}{
+	{"0000000000000000000000000000000000000000000000000000000000000003",
+		"F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9",
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"E907831F80848D1069A5371B402410364BDF1C5F8307B0084C55F1CE2DCA821525F66A4A85EA8B71E482A74F382D2CE5EBEEE8FDB2172F477DF4900D310536C0",
+		true},
+	{"B7E151628AED2A6ABF7158809CF4F3C762E7160F38B4DA56A784D9045190CFEF",
+		"DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"6896BD60EEAE296DB48A229FF71DFE071BDE413E6D43F917DC8DCF8C78DE33418906D11AC976ABCCB20B091292BFF4EA897EFCB639EA871CFA95F6DE339E4B0A",
+		true},
+	{"C90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74020BBEA63B14E5C9",
+		"DD308AFEC5777E13121FA72B9CC1B7CC0139715309B086C960E18FD969774EB8",
+		"7E2D58D8B3BCDF1ABADEC7829054F90DDA9805AAB56C77333024B9D0A508B75C",
+		"5831AAEED7B44BB74E5EAB94BA9D4294C49BCF2A60728D8B4C200F50DD313C1BAB745879A5AD954A72C45A91C3A51D3C7ADEA98D82F8481E0E1E03674A6F3FB7",
+		true},
+	{"0B432B2677937381AEF05BB02A66ECD012773062CF3FA2549E44F58ED2401710",
+		"25D1DFF95105F5253C4022F628A996AD3A0D95FBF21D468A1B33F8C160D8F517",
+		"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF",
+		"7EB0509757E246F19449885651611CB965ECC1A187DD51B64FDA1EDC9637D5EC97582B9CB13DB3933705B32BA982AF5AF25FD78881EBB32771FC5922EFC66EA3",
+		true},
+	{"", "D69C3509BB99E412E68B0FE8544E72837DFA30746D8BE2AA65975F29D22DC7B9",
+		"4DF3C3F68FCC83B27E9D42C90431A72499F17875C81A599B566C9889B9696703",
+		"00000000000000000000003B78CE563F89A0ED9414F5AA28AD0D96D6795F9C6376AFB1548AF603B3EB45C9F8207DEE1060CB71C04E80F593060B07D28308D7F4",
+		true},
+	// public key not on the curve
+	{"", "EEFDEA4CDB677750A420FEE807EACF21EB9898AE79B9768766E4FAA04A2D4A34",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E17776969E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B",
+		false},
+	// R has odd y
+	{"", "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"FFF97BD5755EEEA420453A14355235D382F6472F8568A18B2F057A14602975563CC27944640AC607CD107AE10923D9EF7A73C643E166BE5EBEAFA34B1AC553E2",
+		false},
+	// negated message
+	{"", "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"1FA62E331EDBC21C394792D2AB1100A7B432B013DF3F6FF4F99FCB33E0E1515F28890B3EDB6E7189B630448B515CE4F8622A954CFE545735AAEA5134FCCDB2BD",
+		false},
+	// negated s
+	{"", "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E177769961764B3AA9B2FFCB6EF947B6887A226E8D7C93E00C5ED0C1834FF0D0C2E6DA6",
+		false},
+	// s*G - e*P is infinity
+	{"", "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"0000000000000000000000000000000000000000000000000000000000000000123DDA8328AF9C23A94C1FEECFD123BA4FB73476F0D594DCB65C6425BD186051",
+		false},
+	{"", "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"00000000000000000000000000000000000000000000000000000000000000017615FBAF5AE28864013C099742DEADB4DBA87F11AC6754F93780D5A1837CF197",
+		false},
+	// x of R not on the curve
+	{"", "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"4A298DACAE57395A15D0795DDBFD1DCB564DA82B0F269BC70A74F8220429BA1D69E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B",
+		false},
+	// x of R equals the field size
+	{"", "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC2F69E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B",
+		false},
+	// s equals the curve order
+	{"", "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E177769FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141",
+		false},
+	// public key exceeds the field size
+	{"", "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC30",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E17776969E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B",
+		false},
+}
+
+func TestSchnorrVectors(t *testing.T) {
+	scheme := schnorrScheme{}
+	for i, v := range schnorrVectors {
+		pub := unhex(t, v.pub)
+		if v.priv != "" {
+			derived, err := scheme.Public(unhex(t, v.priv))
+			if err != nil || !derived.Equals(pub) {
+				t.Errorf("vector %v: public key %x, want %x (%v)", i, derived, pub, err)
+			}
+		}
+
+		_, err := scheme.Verify(unhex(t, v.msg), unhex(t, v.sig), pub)
+		if v.valid && err != nil {
+			t.Errorf("vector %v: valid signature rejected, %v", i, err)
+		}
+		if !v.valid && err == nil {
+			t.Errorf("vector %v: invalid signature accepted", i)
+		}
+	}
+}
+
+func TestSchnorrSignVerify(t *testing.T) {
+	scheme := schnorrScheme{}
+	priv, pub, err := scheme.NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := MessageDigest([]byte("schnorr"))
+
+	sig, err := scheme.Sign(digest, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scheme.Verify(digest, sig, pub); err != nil {
+		t.Fatalf("own signature rejected, %v", err)
+	}
+	if _, err := scheme.Verify(MessageDigest([]byte("other")), sig, pub); err == nil {
+		t.Fatal("signature of another digest accepted")
+	}
+}
+
+// s*G == e*P, so R is infinity. Adding a point to its inverse used to crash the node
+func TestSchnorrVerifyInfinity(t *testing.T) {
+	scheme := schnorrScheme{}
+	priv, pub, err := scheme.NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, _, err := schnorrKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := MessageDigest([]byte("infinity"))
+
+	r := Pad32(big.NewInt(1))
+	e := new(big.Int).SetBytes(taggedHash("BIP0340/challenge", r, pub, digest))
+	e.Mod(e, curve.N)
+	s := e.Mul(e, d)
+	s.Mod(s, curve.N)
+
+	if _, err := scheme.Verify(digest, append(r, Pad32(s)...), pub); err == nil {
+		t.Fatal("signature with R at infinity accepted")
+	}
+}
+
+func TestAddPoints(t *testing.T) {
+	gx, gy := curve.Gx, curve.Gy
+	negy := new(big.Int).Sub(curve.P, gy)
+
+	if x, _ := addPoints(gx, gy, gx, negy); x != nil {
+		t.Error("G + -G is not infinity")
+	}
+
+	x, y := addPoints(gx, gy, gx, gy)
+	wantx, wanty := curve.ScalarBaseMult(Pad32(big.NewInt(2)))
+	if x.Cmp(wantx) != 0 || y.Cmp(wanty) != 0 {
+		t.Error("G + G is not 2G")
+	}
+
+	if x, y := addPoints(nil, nil, gx, gy); x.Cmp(gx) != 0 || y.Cmp(gy) != 0 {
+		t.Error("infinity + G is not G")
+	}
+}
+
+// vectors from RFC 8032 section 7.1
+var ed25519Vectors = []struct {
+	priv, pub, msg, sig string
+}{
+	{"9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60",
+		"d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a",
+		"",
+		"e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e065224901555fb8821590a33bacc61e39701cf9b46bd25bf5f0595bbe24655141438e7a100b"},
+	{"4ccd089b28ff96da9db6c346ec114e0f5b8a319f35aba624da8cf6ed4fb8a6fb",
+		"3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c",
+		"72",
+		"92a009a9f0d4cab8720e820b5f642540a2b27b5416503f8fb3762223ebdb69da085ac1e43e15996e458f3613d0f11d8c387b2eaeb4302aeeb00d291612bb0c00"},
+}
+
+func TestEd25519Vectors(t *testing.T) {
+	scheme := ed25519Scheme{}
+	for i, v := range ed25519Vectors {
+		priv, pub, msg, sig := unhex(t, v.priv), unhex(t, v.pub), unhex(t, v.msg), unhex(t, v.sig)
+
+		derived, err := scheme.Public(priv)
+		if err != nil || !derived.Equals(pub) {
+			t.Errorf("vector %v: public key %x, want %x (%v)", i, derived, pub, err)
+		}
+		// ed25519 signatures are deterministic
+		signed, err := scheme.Sign(msg, priv)
+		if err != nil || !signed.Equals(sig) {
+			t.Errorf("vector %v: signature %x, want %x (%v)", i, signed, sig, err)
+		}
+		if _, err := scheme.Verify(msg, sig, pub); err != nil {
+			t.Errorf("vector %v: valid signature rejected, %v", i, err)
+		}
+
+		tampered := append(Hash{}, sig...)
+		tampered[0] ^= 1
+		if _, err := scheme.Verify(msg, tampered, pub); err == nil {
+			t.Errorf("vector %v: tampered signature accepted", i)
+		}
+	}
+}
+
+// S + order is also a valid ed25519 signature, it must be rejected as malleable
+func TestEd25519Canonical(t *testing.T) {
+	scheme := ed25519Scheme{}
+	v := ed25519Vectors[0]
+	sig := unhex(t, v.sig)
+
+	le := func(b []byte) []byte {
+		r := make([]byte, len(b))
+		for i := range b {
+			r[i] = b[len(b)-1-i]
+		}
+		return r
+	}
+	s := new(big.Int).SetBytes(le(sig[32:]))
+	s.Add(s, ed25519Order)
+	malleated := append(Hash{}, sig[:32]...)
+	malleated = append(malleated, le(Pad32(s))...)
+
+	if err := scheme.Canonical(malleated); err == nil {
+		t.Fatal("non canonical S accepted")
+	}
+	if _, err := scheme.Verify(unhex(t, v.msg), malleated, unhex(t, v.pub)); err == nil {
+		t.Fatal("non canonical signature verified")
+	}
+}
@@ -0,0 +1,203 @@
+package blockchain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto/secp256k1"
+)
+
+// schnorrScheme is BIP340 schnorr over secp256k1. Public keys are 32 byte x coordinates
+// (of the point with even y) and signatures are 64 bytes
+type schnorrScheme struct{}
+
+var curve = secp256k1.S256()
+
+// sha256 of the tag hashed twice, then the data (BIP340 tagged hash)
+func taggedHash(tag string, data ...[]byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	sha := sha256.New()
+	sha.Write(tagHash[:])
+	sha.Write(tagHash[:])
+	for _, d := range data {
+		sha.Write(d)
+	}
+	return sha.Sum(nil)
+}
+
+// returns the scalar of a private key, negated if its point has odd y, and the x-only public key
+func schnorrKey(priv Hash) (*big.Int, Hash, error) {
+	d := new(big.Int).SetBytes(priv)
+	if len(priv) != 32 || d.Sign() == 0 || d.Cmp(curve.N) >= 0 {
+		return nil, nil, errors.New("invalid schnorr private key")
+	}
+
+	px, py := curve.ScalarBaseMult(Pad32(d))
+	if py.Bit(0) == 1 {
+		d.Sub(curve.N, d)
+	}
+	return d, Pad32(px), nil
+}
+
+// returns the point with x coordinate and even y
+func liftX(x *big.Int) (*big.Int, *big.Int, error) {
+	if x.Cmp(curve.P) >= 0 {
+		return nil, nil, errors.New("invalid schnorr public key")
+	}
+
+	// y^2 = x^3 + 7, p = 3 mod 4 so y = c^((p+1)/4)
+	c := new(big.Int).Exp(x, big.NewInt(3), curve.P)
+	c.Add(c, curve.B)
+	c.Mod(c, curve.P)
+	exp := new(big.Int).Add(curve.P, big.NewInt(1))
+	exp.Rsh(exp, 2)
+	y := new(big.Int).Exp(c, exp, curve.P)
+
+	if new(big.Int).Exp(y, big.NewInt(2), curve.P).Cmp(c) != 0 {
+		return nil, nil, errors.New("schnorr public key not on curve")
+	}
+	if y.Bit(0) == 1 {
+		y.Sub(curve.P, y)
+	}
+	return x, y, nil
+}
+
+// returns the sum of two points, with nil as the point at infinity. The curve's Add can't
+// add a point to itself or its inverse
+func addPoints(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	if x1 == nil {
+		return x2, y2
+	}
+	if x2 == nil {
+		return x1, y1
+	}
+	if x1.Cmp(x2) == 0 {
+		if y1.Cmp(y2) != 0 {
+			return nil, nil
+		}
+		return curve.Double(x1, y1)
+	}
+	return curve.Add(x1, y1, x2, y2)
+}
+
+// Pad32 returns the big endian bytes of n, left padded to 32 bytes
+func Pad32(n *big.Int) Hash {
+	buf := make([]byte, 32)
+	byts := n.Bytes()
+	copy(buf[32-len(byts):], byts)
+	return Hash(buf)
+}
+
+func (schnorrScheme) NewKey() (Hash, Hash, error) {
+	priv := make([]byte, 32)
+	for {
+		if _, err := rand.Read(priv); err != nil {
+			return nil, nil, err
+		}
+		_, pub, err := schnorrKey(priv)
+		if err == nil {
+			return Hash(priv), pub, nil
+		}
+	}
+}
+
+func (schnorrScheme) Public(priv Hash) (Hash, error) {
+	_, pub, err := schnorrKey(priv)
+	return pub, err
+}
+
+// CheckPublic requires a 32 byte x coordinate of a point on the curve
+func (schnorrScheme) CheckPublic(pub Hash) error {
+	if len(pub) != 32 {
+		return errors.New("invalid schnorr public key length")
+	}
+	_, _, err := liftX(new(big.Int).SetBytes(pub))
+	return err
+}
+
+func (schnorrScheme) Sign(digest Hash, priv Hash) (Hash, error) {
+	d, pub, err := schnorrKey(priv)
+	if err != nil {
+		return nil, err
+	}
+
+	aux := make([]byte, 32)
+	if _, err := rand.Read(aux); err != nil {
+		return nil, err
+	}
+
+	// nonce is derived from the key masked with auxiliary randomness
+	t := Pad32(d)
+	for i, byt := range taggedHash("BIP0340/aux", aux) {
+		t[i] ^= byt
+	}
+	k := new(big.Int).SetBytes(taggedHash("BIP0340/nonce", t, pub, digest))
+	k.Mod(k, curve.N)
+	if k.Sign() == 0 {
+		return nil, errors.New("schnorr nonce is zero")
+	}
+
+	rx, ry := curve.ScalarBaseMult(Pad32(k))
+	if ry.Bit(0) == 1 {
+		k.Sub(curve.N, k)
+	}
+	r := Pad32(rx)
+
+	e := new(big.Int).SetBytes(taggedHash("BIP0340/challenge", r, pub, digest))
+	e.Mod(e, curve.N)
+
+	// s = k + e*d
+	s := e.Mul(e, d)
+	s.Add(s, k)
+	s.Mod(s, curve.N)
+
+	return Hash(append(r, Pad32(s)...)), nil
+}
+
+// Canonical requires 64 bytes (x of R, s) with x below the field size and s below the order
+func (schnorrScheme) Canonical(sig Hash) error {
+	if len(sig) != 64 {
+		return errors.New("invalid schnorr signature length")
+	}
+
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	if r.Cmp(curve.P) >= 0 || s.Cmp(curve.N) >= 0 {
+		return errors.New("signature not canonical")
+	}
+
+	return nil
+}
+
+func (scheme schnorrScheme) Verify(digest Hash, sig Hash, pub Hash) (Hash, error) {
+	if err := scheme.Canonical(sig); err != nil {
+		return nil, err
+	}
+	if len(pub) != 32 {
+		return nil, errors.New("invalid schnorr public key length")
+	}
+
+	px, py, err := liftX(new(big.Int).SetBytes(pub))
+	if err != nil {
+		return nil, err
+	}
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+
+	e := new(big.Int).SetBytes(taggedHash("BIP0340/challenge", sig[:32], pub, digest))
+	e.Mod(e, curve.N)
+	e.Sub(curve.N, e)
+
+	// R = s*G - e*P must not be infinity, and have even y and x equal to r. A zero scalar
+	// multiplies to infinity
+	sx, sy := curve.ScalarBaseMult(Pad32(s))
+	ex, ey := curve.ScalarMult(px, py, Pad32(e))
+	rx, ry := addPoints(sx, sy, ex, ey)
+	if rx == nil || ry.Bit(0) == 1 || rx.Cmp(r) != 0 {
+		return nil, errors.New("signature invalid")
+	}
+
+	return pub, nil
+}
@@ -0,0 +1,74 @@
+package blockchain
+
+import (
+	"container/list"
+	"encoding/binary"
+	"sync"
+)
+
+const sigCacheSize int = 1 << 16 // number of verified signatures remembered
+
+// sigCache remembers the signer of signatures that were already verified, so a transaction
+// is only verified once between the queue and its block. Verification only depends on the
+// signature, so entries stay correct when blocks are removed
+type sigCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List // least recently used at the back
+}
+
+type sigEntry struct {
+	key  string
+	addr Hash
+}
+
+var verified = newSigCache(sigCacheSize)
+
+func newSigCache(size int) *sigCache {
+	return &sigCache{size: size, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+// returns the cache key of a signature, each field is length prefixed so keys are unambiguous
+func sigKey(kt KeyType, digest Hash, sig Hash, pub Hash) string {
+	key := make([]byte, 0, 1+3*binary.MaxVarintLen64+len(digest)+len(sig)+len(pub))
+	key = append(key, byte(kt))
+	lenBuf := make([]byte, binary.MaxVarintLen64)
+	for _, field := range []Hash{digest, sig, pub} {
+		n := binary.PutUvarint(lenBuf, uint64(len(field)))
+		key = append(key, lenBuf[:n]...)
+		key = append(key, field...)
+	}
+	return string(key)
+}
+
+// returns the signer address of a verified signature, false if it isn't cached
+func (c *sigCache) get(key string) (Hash, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, found := c.entries[key]
+	if !found {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*sigEntry).addr, true
+}
+
+// remembers the signer address of a verified signature, evicting the least recently used
+func (c *sigCache) add(key string, addr Hash) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, found := c.entries[key]; found {
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&sigEntry{key: key, addr: addr})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*sigEntry).key)
+	}
+}
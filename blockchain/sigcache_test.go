@@ -0,0 +1,78 @@
+package blockchain
+
+import (
+	"fmt"
+	"testing"
+)
+
+// the cache never holds more than its size, and evicts the least recently used entry
+func TestSigCacheEviction(t *testing.T) {
+	c := newSigCache(3)
+	for _, key := range []string{"a", "b", "c"} {
+		c.add(key, Hash(key))
+	}
+	if _, found := c.get("a"); !found {
+		t.Fatal("a isn't cached")
+	}
+	c.add("c", Hash("c")) // already cached, doesn't grow
+	c.add("d", Hash("d"))
+
+	if len(c.entries) != 3 || c.order.Len() != 3 {
+		t.Fatalf("cache of size 3 holds %v entries and %v in order", len(c.entries), c.order.Len())
+	}
+	if _, found := c.get("b"); found {
+		t.Error("least recently used entry b wasn't evicted")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if addr, found := c.get(key); !found || !addr.Equals(Hash(key)) {
+			t.Errorf("%v evicted or changed", key)
+		}
+	}
+
+	for n := 0; n < 100; n++ {
+		c.add(fmt.Sprint(n), nil)
+	}
+	if len(c.entries) != 3 || c.order.Len() != 3 {
+		t.Fatalf("cache of size 3 holds %v entries after 100 more", len(c.entries))
+	}
+}
+
+func TestSigKeyUnambiguous(t *testing.T) {
+	keys := map[string]string{
+		sigKey(ECDSA, Hash("ab"), Hash("c"), nil):      "digest ab, signature c",
+		sigKey(ECDSA, Hash("a"), Hash("bc"), nil):      "digest a, signature bc",
+		sigKey(ECDSA, Hash("a"), Hash("b"), Hash("c")): "digest a, signature b, key c",
+		sigKey(ECDSA, Hash("a"), nil, Hash("bc")):      "digest a, key bc",
+		sigKey(Ed25519, Hash("a"), Hash("bc"), nil):    "ed25519 digest a, signature bc",
+	}
+	if len(keys) != 5 {
+		t.Fatalf("%v distinct keys of 5 signatures", len(keys))
+	}
+}
+
+// a cached signature only verifies the digest it was verified with, and only itself
+func TestSigCacheNoCrossVerify(t *testing.T) {
+	priv, addr := newKey(t)
+	_, other := newKey(t)
+	trans := signedTransfer(t, priv, addr, other, 10)
+	if err := trans.ValidateSignature(); err != nil {
+		t.Fatal(err)
+	}
+
+	digest := trans
+	digest.Amount = 11 // same signature over another ID
+	if reason := reasonOf(t, digest.ValidateSignature()); reason != BadSignature {
+		t.Errorf("cached signature over another ID rejected as %v, want %v", reason, BadSignature)
+	}
+
+	sig := trans
+	sig.Signature = append(Hash{}, trans.Signature...)
+	sig.Signature[10] ^= 1
+	if reason := reasonOf(t, sig.ValidateSignature()); reason != BadSignature {
+		t.Errorf("another signature of a cached ID rejected as %v, want %v", reason, BadSignature)
+	}
+
+	if err := trans.ValidateSignature(); err != nil {
+		t.Fatalf("cached signature rejected, %v", err)
+	}
+}
@@ -0,0 +1,258 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Account is the balance of an address in a snapshot
+type Account struct {
+	Addr    Hash
+	Balance int64
+}
+
+// Snapshot is the account state of the chain at a height, enough to validate the blocks
+// after it without replaying the chain
+type Snapshot struct {
+	Height       uint64    // height of the last block applied to the state
+	Top          Block     // header of the block at Height (without transactions)
+	FilterHeader Hash      // filter header of the block at Height
+	Accounts     []Account // non-zero balances, sorted by address
+	TXIDs        []Hash    // TXIDs used up to Height, sorted
+	Channels     []Channel // payment channels opened up to Height, sorted by id
+}
+
+// Snapshot returns the account state at height, which must be in the chain and not below
+// the snapshot the chain was loaded from (if any)
+func (bc *Blockchain) Snapshot(height uint64) (*Snapshot, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.snapshot(height)
+}
+
+// snapshot is Snapshot without locking, must hold the lock
+func (bc *Blockchain) snapshot(height uint64) (*Snapshot, error) {
+	if height == 0 || height < bc.base || height > bc.height {
+		return nil, fmt.Errorf("no account state for height %v", height)
+	}
+
+	balances := make(map[string]int64, len(bc.balances))
+	for addr, bal := range bc.balances {
+		balances[addr] = bal
+	}
+	txids := make(map[string]struct{}, len(bc.txids))
+	for txid := range bc.txids {
+		txids[txid] = struct{}{}
+	}
+	channels := make(map[string]Channel, len(bc.channels))
+	for id, c := range bc.channels {
+		channels[id] = *c
+	}
+
+	// undo the blocks above height, from the top down
+	for h := bc.height; h > height; h-- {
+		undo := bc.undo[h]
+		for addr, change := range undo.balances {
+			balances[addr] -= change
+		}
+		for _, txid := range undo.txids {
+			delete(txids, txid)
+		}
+		for _, id := range undo.settled {
+			c := channels[id]
+			c.Settled = false
+			channels[id] = c
+		}
+		for _, id := range undo.opened {
+			delete(channels, id)
+		}
+	}
+
+	s := Snapshot{Height: height, Top: *bc.blocks[height], FilterHeader: bc.filters[height].Header}
+	s.Top.Transactions = nil
+
+	for addr, bal := range balances {
+		if bal != 0 {
+			s.Accounts = append(s.Accounts, Account{Addr: Hash(addr), Balance: bal})
+		}
+	}
+	sort.Slice(s.Accounts, func(i, j int) bool {
+		return bytes.Compare(s.Accounts[i].Addr, s.Accounts[j].Addr) < 0
+	})
+
+	for txid := range txids {
+		s.TXIDs = append(s.TXIDs, Hash(txid))
+	}
+	sort.Slice(s.TXIDs, func(i, j int) bool { return bytes.Compare(s.TXIDs[i], s.TXIDs[j]) < 0 })
+
+	for _, c := range channels {
+		s.Channels = append(s.Channels, c)
+	}
+	sort.Slice(s.Channels, func(i, j int) bool {
+		return bytes.Compare(s.Channels[i].ID, s.Channels[j].ID) < 0
+	})
+
+	return &s, nil
+}
+
+// writes a variable length field prefixed with its length, so fields can't run together
+func writeField(buf *bytes.Buffer, field Hash) {
+	binary.Write(buf, binary.LittleEndian, uint32(len(field)))
+	buf.Write(field)
+}
+
+// Hash double sha3-256 hashes the height, top block hash, filter header, accounts, TXIDs,
+// and channels of the snapshot. Each list is prefixed with its count and each variable
+// length field with its length, so the encoding reads only one way
+func (s *Snapshot) Hash() (Hash, error) {
+	topHash, err := s.Top.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, s.Height)
+	writeField(buf, topHash)
+	writeField(buf, s.FilterHeader)
+	binary.Write(buf, binary.LittleEndian, uint32(len(s.Accounts)))
+	for _, acc := range s.Accounts {
+		writeField(buf, acc.Addr)
+		binary.Write(buf, binary.LittleEndian, acc.Balance)
+	}
+	binary.Write(buf, binary.LittleEndian, uint32(len(s.TXIDs)))
+	for _, txid := range s.TXIDs {
+		writeField(buf, txid)
+	}
+	binary.Write(buf, binary.LittleEndian, uint32(len(s.Channels)))
+	for _, c := range s.Channels {
+		writeField(buf, c.ID)
+		writeField(buf, c.Funder)
+		writeField(buf, c.Payee)
+		binary.Write(buf, binary.LittleEndian, c.Deposit)
+		binary.Write(buf, binary.LittleEndian, c.Expiry)
+		binary.Write(buf, binary.LittleEndian, c.Settled)
+	}
+
+	sha := sha3.New256()
+	if _, err := sha.Write(buf.Bytes()); err != nil {
+		return nil, err
+	}
+
+	first := sha.Sum(nil)
+	sha = sha3.New256()
+	if _, err := sha.Write(first); err != nil {
+		return nil, err
+	}
+
+	return sha.Sum(nil), nil
+}
+
+// returns true if the n keys are strictly ascending, sorted without repeats
+func ascending(n int, key func(i int) Hash) bool {
+	for i := 1; i < n; i++ {
+		if bytes.Compare(key(i-1), key(i)) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// TrustedSnapshot returns the height and hash of the snapshot a new node may bootstrap
+// from, false if none is configured
+func TrustedSnapshot() (uint64, Hash, bool) {
+	heightStr, hashStr := os.Getenv("_I32COIN_SNAPSHOT_HEIGHT"), os.Getenv("_I32COIN_SNAPSHOT_HASH")
+	if heightStr == "" || hashStr == "" {
+		return 0, nil, false
+	}
+
+	height, err := strconv.ParseUint(heightStr, 10, 64)
+	if err != nil {
+		log.Fatal("blockchain fatal: could not determine snapshot height")
+	}
+	hash, err := hex.DecodeString(hashStr)
+	if err != nil || len(hash) != shaHashSize {
+		log.Fatal("blockchain fatal: could not determine snapshot hash")
+	}
+
+	return height, hash, true
+}
+
+// LoadSnapshot replaces the chain with the account state of a snapshot matching the
+// trusted snapshot. Blocks are validated from the snapshot height on
+func (bc *Blockchain) LoadSnapshot(s *Snapshot) error {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	return bc.loadSnapshot(s)
+}
+
+// loadSnapshot is LoadSnapshot without locking, must hold the write lock
+func (bc *Blockchain) loadSnapshot(s *Snapshot) error {
+	height, trusted, ok := TrustedSnapshot()
+	if !ok {
+		return fmt.Errorf("no trusted snapshot configured")
+	}
+	if s.Height != height || s.Top.Height != height {
+		return invalid(BadSnapshot, "snapshot height is %v, trusted %v", s.Height, height)
+	}
+	if bc.height >= height {
+		return fmt.Errorf("chain is already at height %v", bc.height)
+	}
+	if !ascending(len(s.Accounts), func(i int) Hash { return s.Accounts[i].Addr }) ||
+		!ascending(len(s.TXIDs), func(i int) Hash { return s.TXIDs[i] }) ||
+		!ascending(len(s.Channels), func(i int) Hash { return s.Channels[i].ID }) {
+		return invalid(BadSnapshot, "snapshot entries are not sorted or repeat")
+	}
+
+	hash, err := s.Hash()
+	if err != nil {
+		return invalid(Malformed, "could not hash snapshot, %v", err)
+	}
+	if !hash.Equals(trusted) {
+		return invalid(BadSnapshot, "snapshot hash mismatch")
+	}
+	blockHash, err := s.Top.Hash()
+	if err != nil {
+		return invalid(Malformed, "could not hash snapshot block, %v", err)
+	}
+	if checkpoint, found := Checkpoints()[height]; found && !blockHash.Equals(checkpoint) {
+		return invalid(BadSnapshot, "snapshot block conflicts with checkpoint")
+	}
+
+	top := s.Top
+	top.Transactions = nil
+	bc.blocks = map[uint64]*Block{height: &top}
+	bc.filters = map[uint64]*Filter{height: {Height: height, BlockHash: blockHash, Header: s.FilterHeader}}
+	bc.undo = make(map[uint64]*blockUndo)
+	bc.assumed = nil
+	bc.index = newIndex()
+	bc.index.add(&top)
+
+	bc.balances = make(map[string]int64, len(s.Accounts))
+	for _, acc := range s.Accounts {
+		bc.balances[string(acc.Addr)] = acc.Balance
+	}
+	bc.txids = make(map[string]struct{}, len(s.TXIDs))
+	for _, txid := range s.TXIDs {
+		bc.txids[string(txid)] = struct{}{}
+	}
+	bc.channels = make(map[string]*Channel, len(s.Channels))
+	for ndx := range s.Channels {
+		c := s.Channels[ndx]
+		bc.channels[string(c.ID)] = &c
+	}
+
+	bc.height = height
+	bc.base = height
+	bc.purgeQueued(nil)
+	log.Printf("blockchain: loaded snapshot at %v\n", height)
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// trusts the snapshot at height with hash until the test ends
+func setTrustedSnapshot(t *testing.T, height uint64, hash Hash) {
+	t.Helper()
+	os.Setenv("_I32COIN_SNAPSHOT_HEIGHT", fmt.Sprint(height))
+	os.Setenv("_I32COIN_SNAPSHOT_HASH", hex.EncodeToString(hash))
+	t.Cleanup(func() {
+		os.Unsetenv("_I32COIN_SNAPSHOT_HEIGHT")
+		os.Unsetenv("_I32COIN_SNAPSHOT_HASH")
+	})
+}
+
+// returns the genesis transaction, a chain of two blocks on it, the transfer in block 1 and
+// the private key of its sender
+func snapshotChain(t *testing.T) (Transaction, *Blockchain, Transaction, Hash) {
+	t.Helper()
+	priv, addr := newKey(t)
+	_, miner := newKey(t)
+	genesis := NewTransaction(RootHash(), addr, 100)
+	bc := NewBlockchain(genesis)
+
+	spent := signedTransfer(t, priv, addr, miner, 10)
+	for _, trans := range []Transaction{spent, signedTransfer(t, priv, addr, miner, 20)} {
+		if err := bc.AddBlock(nextBlock(t, bc, miner, trans)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return genesis, bc, spent, priv
+}
+
+func snapshotHash(t *testing.T, s *Snapshot) Hash {
+	t.Helper()
+	hash, err := s.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+// a loaded snapshot has the state it was taken from, and remembers the used TXIDs
+func TestSnapshotRoundTrip(t *testing.T) {
+	genesis, bc, spent, priv := snapshotChain(t)
+	s, err := bc.Snapshot(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setTrustedSnapshot(t, 2, snapshotHash(t, s))
+
+	loaded := NewBlockchain(genesis)
+	if err := loaded.LoadSnapshot(s); err != nil {
+		t.Fatal(err)
+	}
+	for _, acc := range s.Accounts {
+		if bal := loaded.Balance(acc.Addr); bal != acc.Balance {
+			t.Errorf("balance %v after loading, want %v", bal, acc.Balance)
+		}
+	}
+	again, err := loaded.Snapshot(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !snapshotHash(t, again).Equals(snapshotHash(t, s)) {
+		t.Error("snapshot of the loaded state differs")
+	}
+
+	_, miner := newKey(t)
+	if err := loaded.AddBlock(nextBlock(t, loaded, miner, spent)); err == nil {
+		t.Error("transaction replayed after loading the snapshot")
+	}
+	next := signedTransfer(t, priv, spent.Sender, miner, 1)
+	if err := loaded.AddBlock(nextBlock(t, loaded, miner, next)); err != nil {
+		t.Errorf("block on the snapshot rejected, %v", err)
+	}
+}
+
+// fields and entries can't run together into another snapshot with the same hash
+func TestSnapshotHashCollision(t *testing.T) {
+	_, bc, _, _ := snapshotChain(t)
+	s, err := bc.Snapshot(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	joined, split := *s, *s
+	split.TXIDs = []Hash{Hash("a"), Hash("b")}
+	joined.TXIDs = []Hash{Hash("ab")}
+	if snapshotHash(t, &joined).Equals(snapshotHash(t, &split)) {
+		t.Error("TXIDs [a, b] and [ab] hash the same")
+	}
+
+	joined, split = *s, *s
+	split.Channels = []Channel{{ID: Hash("c"), Funder: Hash("a"), Payee: Hash("bc")}}
+	joined.Channels = []Channel{{ID: Hash("c"), Funder: Hash("ab"), Payee: Hash("c")}}
+	if snapshotHash(t, &joined).Equals(snapshotHash(t, &split)) {
+		t.Error("channel fields hash the same when shifted")
+	}
+
+	joined, split = *s, *s
+	split.TXIDs, split.Accounts = nil, []Account{{Addr: Hash("a")}}
+	joined.TXIDs, joined.Accounts = []Hash{Hash("a")}, nil
+	if snapshotHash(t, &joined).Equals(snapshotHash(t, &split)) {
+		t.Error("an entry hashes the same in another list")
+	}
+}
+
+// entries must be sorted without repeats, even in a snapshot matching the trusted hash
+func TestSnapshotUnsorted(t *testing.T) {
+	genesis, bc, _, _ := snapshotChain(t)
+	s, err := bc.Snapshot(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.TXIDs) < 2 {
+		t.Fatalf("snapshot has %v TXIDs, want at least 2", len(s.TXIDs))
+	}
+
+	swapped := *s
+	swapped.TXIDs = append([]Hash{s.TXIDs[1], s.TXIDs[0]}, s.TXIDs[2:]...)
+	repeated := *s
+	repeated.Accounts = append([]Account{s.Accounts[0]}, s.Accounts...)
+
+	for _, bad := range []*Snapshot{&swapped, &repeated} {
+		setTrustedSnapshot(t, 2, snapshotHash(t, bad))
+		err := NewBlockchain(genesis).LoadSnapshot(bad)
+		if reason := reasonOf(t, err); reason != BadSnapshot {
+			t.Errorf("rejected as %v, want %v", reason, BadSnapshot)
+		}
+	}
+}
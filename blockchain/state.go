@@ -0,0 +1,84 @@
+package blockchain
+
+import (
+	"log"
+)
+
+// blockUndo records what a block changed in the account state, so it can be removed
+// without its transactions (which may have been pruned)
+type blockUndo struct {
+	balances map[string]int64 // change in balance, indexed by address
+	txids    []string         // TXIDs first used in the block
+	opened   []string         // channels opened in the block
+	settled  []string         // channels settled in the block
+}
+
+// applies a block added to the top of the chain to the account state
+func (bc *Blockchain) applyBlock(b *Block) {
+	undo := blockUndo{balances: make(map[string]int64)}
+
+	for _, trans := range b.Transactions {
+		// balances change before channels, a close needs its channel's deposit
+		for ndx, addr := range []Hash{trans.Sender, trans.Reciever} {
+			if ndx == 1 && addr.Equals(trans.Sender) {
+				continue // change was already counted for the sender
+			}
+			change := bc.balanceChange(trans, addr)
+			bc.balances[string(addr)] += change
+			undo.balances[string(addr)] += change
+		}
+
+		bc.txids[string(trans.TXID)] = struct{}{}
+		undo.txids = append(undo.txids, string(trans.TXID))
+
+		switch trans.Kind {
+		case ChannelOpen:
+			id, err := trans.ID()
+			if err != nil {
+				log.Println("blockchain: could not index channel, ", err)
+				break
+			}
+			bc.channels[string(id)] = &Channel{ID: id, Funder: trans.Sender,
+				Payee: trans.Reciever, Deposit: trans.Amount, Expiry: trans.Expiry}
+			undo.opened = append(undo.opened, string(id))
+			break
+		case ChannelClose, ChannelRefund:
+			c, found := bc.channels[string(trans.Channel)]
+			if found {
+				c.Settled = true
+				undo.settled = append(undo.settled, string(trans.Channel))
+			} else {
+				log.Println("blockchain: settled channel is missing from index")
+			}
+			break
+		}
+	}
+
+	bc.undo[b.Height] = &undo
+}
+
+// reverts the account state changes of the block at height, which must be the top
+func (bc *Blockchain) revertBlock(height uint64) {
+	undo, found := bc.undo[height]
+	if !found {
+		log.Fatal("blockchain fatal: missing undo data for block ", height)
+	}
+
+	for addr, change := range undo.balances {
+		bc.balances[addr] -= change
+		if bc.balances[addr] == 0 {
+			delete(bc.balances, addr)
+		}
+	}
+	for _, txid := range undo.txids {
+		delete(bc.txids, txid)
+	}
+	for _, id := range undo.settled {
+		bc.channels[id].Settled = false
+	}
+	for _, id := range undo.opened {
+		delete(bc.channels, id)
+	}
+
+	delete(bc.undo, height)
+}
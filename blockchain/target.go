@@ -0,0 +1,110 @@
+package blockchain
+
+import (
+	"errors"
+	"log"
+	"math/big"
+	"os"
+	"strconv"
+)
+
+// largest target plus one, targets are 256-bit integers
+var targetLimit = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// CompactToBig decodes compact "bits" into a target. The top byte is the size of the target
+// in bytes and the low 3 bytes are its most significant bytes, like Bitcoin's nBits
+func CompactToBig(bits uint32) (*big.Int, error) {
+	mantissa := int64(bits & 0x007fffff)
+	exponent := uint(bits >> 24)
+
+	if bits&0x00800000 != 0 && mantissa != 0 {
+		return nil, errors.New("target is negative")
+	}
+
+	target := big.NewInt(mantissa)
+	if exponent <= 3 {
+		target.Rsh(target, 8*(3-exponent))
+	} else {
+		target.Lsh(target, 8*(exponent-3))
+	}
+
+	if target.Sign() == 0 {
+		return nil, errors.New("target is zero")
+	}
+	if target.Cmp(targetLimit) >= 0 {
+		return nil, errors.New("target overflows 256 bits")
+	}
+	return target, nil
+}
+
+// BigToCompact encodes a target as compact "bits", keeping its 3 most significant bytes
+func BigToCompact(target *big.Int) uint32 {
+	exponent := uint(len(target.Bytes()))
+
+	var mantissa uint32
+	if exponent <= 3 {
+		mantissa = uint32(target.Uint64() << (8 * (3 - exponent)))
+	} else {
+		mantissa = uint32(new(big.Int).Rsh(target, 8*(exponent-3)).Uint64())
+	}
+
+	// the sign bit of the mantissa must be clear, move a byte into the exponent
+	if mantissa&0x00800000 != 0 {
+		mantissa >>= 8
+		exponent++
+	}
+
+	return uint32(exponent)<<24 | mantissa
+}
+
+// HashToBig interprets a hash as a little-endian 256-bit integer
+func HashToBig(hash Hash) *big.Int {
+	be := make([]byte, len(hash))
+	for i, byt := range hash {
+		be[len(hash)-1-i] = byt
+	}
+	return new(big.Int).SetBytes(be)
+}
+
+// TargetBits returns the compact target every block must have. _I32COIN_BITS is the hex
+// compact target, otherwise _I32COIN_DIFFICULTY is the number of low 0xFF bytes of the target
+func TargetBits() uint32 {
+	if hexBits := os.Getenv("_I32COIN_BITS"); hexBits != "" {
+		bits, err := strconv.ParseUint(hexBits, 16, 32)
+		if err != nil {
+			log.Fatal("blockchain fatal: could not determine target bits")
+		}
+		if _, err := CompactToBig(uint32(bits)); err != nil {
+			log.Fatal("blockchain fatal: invalid target bits, ", err)
+		}
+		return uint32(bits)
+	}
+
+	diff, err := strconv.Atoi(os.Getenv("_I32COIN_DIFFICULTY"))
+	if err != nil || diff < 1 || diff > shaHashSize {
+		log.Fatal("fatal: could not determine difficulty")
+	}
+
+	// 2^(8*diff) - 1, the target of diff 0xFF bytes
+	target := new(big.Int).Lsh(big.NewInt(1), uint(8*diff))
+	target.Sub(target, big.NewInt(1))
+	return BigToCompact(target)
+}
+
+// Target returns the 256-bit target decoded from the block's bits, nil if they're invalid
+func (b *Block) Target() *big.Int {
+	target, err := CompactToBig(b.Bits)
+	if err != nil {
+		return nil
+	}
+	return target
+}
+
+// Work returns the expected number of hashes to mine the block, 2^256 / (target + 1)
+func (b *Block) Work() *big.Int {
+	target := b.Target()
+	if target == nil {
+		return big.NewInt(0)
+	}
+	return new(big.Int).Div(targetLimit, new(big.Int).Add(target, big.NewInt(1)))
+}
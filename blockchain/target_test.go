@@ -0,0 +1,175 @@
+package blockchain
+
+import (
+	"math/big"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func bigHex(t *testing.T, s string) *big.Int {
+	t.Helper()
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		t.Fatalf("bad hex %v", s)
+	}
+	return n
+}
+
+func TestCompactToBig(t *testing.T) {
+	for bits, want := range map[uint32]string{
+		0x01123456: "12",
+		0x02123456: "1234",
+		0x03123456: "123456",
+		0x04123456: "12345600",
+		0x02008000: "80",
+		0x207fffff: "7fffff" + "0000000000000000000000000000000000000000000000000000000000",
+		0x2100ffff: "ffff" + "000000000000000000000000000000000000000000000000000000000000",
+	} {
+		target, err := CompactToBig(bits)
+		if err != nil {
+			t.Errorf("%08x rejected, %v", bits, err)
+			continue
+		}
+		if target.Cmp(bigHex(t, want)) != 0 {
+			t.Errorf("%08x decoded to %x, want %v", bits, target, want)
+		}
+	}
+}
+
+func TestCompactToBigInvalid(t *testing.T) {
+	for bits, reason := range map[uint32]string{
+		0x00000000: "zero",
+		0x01003456: "zero, the mantissa is shifted out",
+		0x04800000: "zero with the sign bit",
+		0x04923456: "negative",
+		0x01fedcba: "negative",
+		0x21010000: "2^256, overflows",
+		0x22000100: "2^256, overflows",
+		0xff123456: "overflows",
+	} {
+		if target, err := CompactToBig(bits); err == nil {
+			t.Errorf("%08x accepted as %x, %v", bits, target, reason)
+		}
+	}
+}
+
+// normalized bits encode to themselves, including those whose mantissa needed a byte moved
+// into the exponent to keep the sign bit clear
+func TestCompactRoundTrip(t *testing.T) {
+	for _, bits := range []uint32{0x01120000, 0x02008000, 0x03123456, 0x05009234, 0x1d00ffff, 0x1e00ffff, 0x207fffff, 0x2100ffff} {
+		target, err := CompactToBig(bits)
+		if err != nil {
+			t.Fatalf("%08x rejected, %v", bits, err)
+		}
+		if encoded := BigToCompact(target); encoded != bits {
+			t.Errorf("%08x encoded again as %08x", bits, encoded)
+		}
+	}
+}
+
+// only the 3 most significant bytes are kept, and the sign bit is never set
+func TestBigToCompact(t *testing.T) {
+	for s, want := range map[string]uint32{
+		"12":       0x01120000,
+		"80":       0x02008000,
+		"ff":       0x0200ff00,
+		"12345678": 0x04123456,
+		"ffffffff": 0x0500ffff,
+		"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff": 0x2100ffff,
+	} {
+		bits := BigToCompact(bigHex(t, s))
+		if bits != want {
+			t.Errorf("%v encoded as %08x, want %08x", s, bits, want)
+		}
+		if bits&0x00800000 != 0 {
+			t.Errorf("%v encoded with the sign bit set", s)
+		}
+		if target, err := CompactToBig(bits); err != nil || target.Cmp(bigHex(t, s)) > 0 {
+			t.Errorf("%v encoded above itself (%v)", s, err)
+		}
+	}
+}
+
+func TestHashToBig(t *testing.T) {
+	one := make(Hash, shaHashSize)
+	one[0] = 1
+	if n := HashToBig(one); n.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("first byte 1 is %v, want 1", n)
+	}
+
+	top := make(Hash, shaHashSize)
+	top[shaHashSize-1] = 0x80
+	if n := HashToBig(top); n.Cmp(new(big.Int).Lsh(big.NewInt(1), 255)) != 0 {
+		t.Errorf("last byte 0x80 is %x, want 2^255", n)
+	}
+}
+
+// a hash equal to the target meets it, one more does not
+func TestHashTargetBoundary(t *testing.T) {
+	target, err := CompactToBig(0x1d00ffff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	be := Pad32(target)
+	hash := make(Hash, shaHashSize)
+	for i, byt := range be {
+		hash[shaHashSize-1-i] = byt
+	}
+	if HashToBig(hash).Cmp(target) != 0 {
+		t.Fatalf("hash of the target is %x, want %x", HashToBig(hash), target)
+	}
+
+	hash[0]++
+	if HashToBig(hash).Cmp(target) <= 0 {
+		t.Fatal("hash above the target meets it")
+	}
+}
+
+func TestWork(t *testing.T) {
+	for bits, want := range map[uint32]*big.Int{
+		0x1d00ffff: big.NewInt(0x100010001),
+		0x207fffff: big.NewInt(2),
+		0x04923456: big.NewInt(0), // negative target
+		0x00000000: big.NewInt(0),
+	} {
+		if work := (&Block{Bits: bits}).Work(); work.Cmp(want) != 0 {
+			t.Errorf("work of %08x is %v, want %v", bits, work, want)
+		}
+	}
+
+	easy, hard := &Block{Bits: 0x1e00ffff}, &Block{Bits: 0x1d00ffff}
+	if easy.Work().Cmp(hard.Work()) >= 0 {
+		t.Error("a larger target has at least as much work")
+	}
+}
+
+// _I32COIN_DIFFICULTY is the number of low 0xFF bytes of the target
+func TestTargetBitsDifficulty(t *testing.T) {
+	bits := os.Getenv("_I32COIN_BITS")
+	os.Unsetenv("_I32COIN_BITS")
+	t.Cleanup(func() {
+		os.Setenv("_I32COIN_BITS", bits)
+		os.Unsetenv("_I32COIN_DIFFICULTY")
+	})
+
+	for diff, want := range map[int]uint32{
+		1:  0x0200ff00,
+		2:  0x0300ffff,
+		3:  0x0400ffff,
+		4:  0x0500ffff,
+		32: 0x2100ffff,
+	} {
+		os.Setenv("_I32COIN_DIFFICULTY", strconv.Itoa(diff))
+		got := TargetBits()
+		if got != want {
+			t.Errorf("difficulty %v is bits %08x, want %08x", diff, got, want)
+		}
+
+		limit := new(big.Int).Lsh(big.NewInt(1), uint(8*diff))
+		if target, err := CompactToBig(got); err != nil || target.Cmp(limit) >= 0 {
+			t.Errorf("difficulty %v has a target above 2^%v - 1 (%v)", diff, 8*diff, err)
+		}
+	}
+}
@@ -2,24 +2,30 @@ package blockchain
 
 import (
 	"crypto/rand"
-	"errors"
 	"fmt"
 	"log"
 
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/crypto/secp256k1"
 	"golang.org/x/crypto/sha3"
 )
 
 // Transaction is transaction in the block chain
 type Transaction struct {
-	Seq       uint32 // sequence number in block, reward has seq of 0
+	Seq       uint32 // position in block, reward has seq of 0. Not part of the ID
 	Sender    Hash   // public key of sender (wallet addr)
 	Reciever  Hash   // public key of reciever (wallet addr)
 	Amount    uint32 // amount of i32coins
+	Fee       uint32 // paid by the sender to the miner of the block, on top of the amount
 	Signature Hash   // signature of sender
 	//Height    uint64
-	TXID Hash
+	TXID     Hash
+	Kind     TxKind  // kind of transaction, plain transfers are the zero value
+	Channel  Hash    // payment channel id, for channel close and refund
+	Expiry   uint64  // height from which an opened channel may be refunded
+	StateSig Hash    // counterparty's signature over the state closing a channel
+	StateKey KeyType // signature scheme of the state signer's key
+	StatePub Hash    // public key of the state signer, for schemes that can't recover it
+	KeyType  KeyType // signature scheme of the sender's key
+	PubKey   Hash    // public key of the sender, for schemes that can't recover it
 }
 
 // NewTransaction generates new transaction without a seq or signature
@@ -41,46 +47,71 @@ func genTXID() (Hash, error) {
 	return txid, nil
 }
 
-// Sign generates signature for transaction digest (sender, reciever, amount, and TXID)
+// Sign generates signature for transaction digest (sender, reciever, amount, fee, and TXID)
+// with the scheme of the transaction's key type
 func (t *Transaction) Sign(priv Hash) error {
+	scheme, err := Scheme(t.KeyType)
+	if err != nil {
+		return err
+	}
+
+	if t.KeyType != ECDSA {
+		t.PubKey, err = scheme.Public(priv)
+		if err != nil {
+			return err
+		}
+	}
+
 	digest, err := t.digest()
 	if err != nil {
 		return err
 	}
 
-	sig, err := secp256k1.Sign(digest, priv)
+	sig, err := scheme.Sign(digest, priv)
 	if err != nil {
 		return err
 	}
+	if err := scheme.Canonical(sig); err != nil {
+		return err
+	}
 	t.Signature = sig
 
 	return nil
 }
 
 func (t *Transaction) String() string {
-	return fmt.Sprintf("%v,%v,%v,%v,%v,%v", t.Seq, t.Sender, t.Reciever, t.Amount, t.Signature, t.TXID)
-}
-
-// double hashs all fields (sha3-256)
-func (t *Transaction) hash() (Hash, error) {
-	str := t.String()
-
-	sha := sha3.New256()
-	if _, err := sha.Write([]byte(str)); err != nil {
-		return nil, err
+	str := fmt.Sprintf("%v,%v,%v,%v,%v,%v", t.Seq, t.Sender, t.Reciever, t.Amount, t.Signature, t.TXID)
+	if t.Kind != Transfer {
+		str += fmt.Sprintf(",%v,%v,%v,%v,%v,%v", t.Kind, t.Channel, t.Expiry, t.StateSig, t.StateKey, t.StatePub)
 	}
-
-	first := sha.Sum(nil)
-	sha = sha3.New256()
-	if _, err := sha.Write(first); err != nil {
-		return nil, err
+	if t.KeyType != ECDSA {
+		str += fmt.Sprintf(",%v,%v", t.KeyType, t.PubKey)
 	}
+	if t.Fee != 0 {
+		str += fmt.Sprintf(",fee=%v", t.Fee)
+	}
+	return str
+}
 
-	return sha.Sum(nil), nil
+// ID is the identity of the transaction, the digest of its signed fields. Neither the
+// signature nor the position in a block are part of it, so the ID stays the same however
+// the transaction is signed and wherever it lands
+func (t *Transaction) ID() (Hash, error) {
+	return t.digest()
 }
 
 func (t *Transaction) predigest() Hash {
-	return []byte(fmt.Sprintf(fmt.Sprintf("%v,%v,%v,%v", t.Sender, t.Reciever, t.Amount, t.TXID)))
+	str := fmt.Sprintf("%v,%v,%v,%v", t.Sender, t.Reciever, t.Amount, t.TXID)
+	if t.Kind != Transfer { // transfers keep their original digest
+		str += fmt.Sprintf(",%v,%v,%v", t.Kind, t.Channel, t.Expiry)
+	}
+	if t.KeyType != ECDSA { // as do ecdsa signatures
+		str += fmt.Sprintf(",%v,%v", t.KeyType, t.PubKey)
+	}
+	if t.Fee != 0 { // and transactions without a fee
+		str += fmt.Sprintf(",fee=%v", t.Fee)
+	}
+	return []byte(str)
 }
 
 // only (double sha3-256) hashes sender, reciever, amount, and height (twice)
@@ -99,33 +130,34 @@ func (t *Transaction) digest() (Hash, error) {
 	return sha.Sum(nil), nil
 }
 
-// Equals returns true if both transactions have the same values
+// Equals returns true if both transactions have the same ID
 func (t *Transaction) Equals(other Transaction) bool {
-	return t.Sender.Equals(other.Sender) && t.Reciever.Equals(other.Reciever) && t.Seq == other.Seq &&
-		t.Amount == other.Amount && t.Signature.Equals(other.Signature)
+	id, err := t.ID()
+	if err != nil {
+		return false
+	}
+	otherID, err := other.ID()
+	if err != nil {
+		return false
+	}
+	return id.Equals(otherID)
 }
 
-// ValidateSignature validates transaction was signed by the sender
+// ValidateSignature validates transaction was signed by the sender, with the scheme of
+// the transaction's key type
 func (t *Transaction) ValidateSignature() error {
 	digest, err := t.digest()
 	if err != nil {
-		return err
+		return invalid(Malformed, "could not hash transaction, %v", err)
 	}
 
-	// get public key of signature
-	sigpub, err := crypto.SigToPub(digest, t.Signature)
+	addr, err := signerAddr(t.KeyType, digest, t.Signature, t.PubKey)
 	if err != nil {
-		return err
+		return invalid(BadSignature, "%v", err)
 	}
-	sigpubHash := Hash(crypto.FromECDSAPub(sigpub))
-
-	// convert signature public key to address
-	sha := sha3.New256()
-	sha.Write(sigpubHash)
-	addr := Hash(sha.Sum(nil))
 
 	if !addr.Equals(t.Sender) {
-		return errors.New("signature invalid")
+		return invalid(BadSignature, "signature invalid")
 	}
 
 	return nil
@@ -0,0 +1,70 @@
+package blockchain
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// fields the ID and merkle leaf don't commit to must be empty, or a relayer could change
+// them without changing the block hash
+func TestUnusedFieldsRejected(t *testing.T) {
+	priv, addr := newKey(t)
+	_, other := newKey(t)
+	bc := newTestChain(addr, 100)
+
+	for name, junk := range map[string]func(*Transaction){
+		"ecdsa public key": func(trans *Transaction) { trans.PubKey = make(Hash, 1<<20) },
+		"channel":          func(trans *Transaction) { trans.Channel = Hash("junk") },
+		"expiry":           func(trans *Transaction) { trans.Expiry = 7 },
+		"state signature":  func(trans *Transaction) { trans.StateSig = Hash("junk") },
+		"state key type":   func(trans *Transaction) { trans.StateKey = Ed25519 },
+		"state public key": func(trans *Transaction) { trans.StatePub = Hash("junk") },
+	} {
+		trans := signedTransfer(t, priv, addr, other, 10)
+		junk(&trans)
+		if err := trans.ValidateSignature(); err != nil {
+			t.Fatalf("%v: signature no longer valid, %v", name, err)
+		}
+		err := bc.AddBlock(nextBlock(t, bc, other, trans))
+		if reason := reasonOf(t, err); reason != Malformed {
+			t.Errorf("transfer with junk %v rejected as %v, want %v", name, reason, Malformed)
+		}
+	}
+
+	if err := bc.AddBlock(nextBlock(t, bc, other, signedTransfer(t, priv, addr, other, 10))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// (r, n-s) with the flipped recovery id recovers the same key, only low s is accepted
+func TestECDSAHighSRejected(t *testing.T) {
+	priv, addr := newKey(t)
+	_, other := newKey(t)
+	bc := newTestChain(addr, 100)
+
+	trans := signedTransfer(t, priv, addr, other, 10)
+	sig := append(Hash{}, trans.Signature...)
+	s := new(big.Int).SetBytes(sig[32:64])
+	copy(sig[32:64], Pad32(s.Sub(crypto.S256().Params().N, s)))
+	sig[64] ^= 1
+
+	digest, err := trans.ID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := crypto.SigToPub(digest, sig)
+	if err != nil || !Address(ECDSA, crypto.FromECDSAPub(pub)).Equals(addr) {
+		t.Fatalf("high s signature does not recover the sender (%v)", err)
+	}
+
+	trans.Signature = sig
+	if reason := reasonOf(t, trans.ValidateSignature()); reason != BadSignature {
+		t.Fatalf("high s signature rejected as %v, want %v", reason, BadSignature)
+	}
+	err = bc.AddBlock(nextBlock(t, bc, other, trans))
+	if reason := reasonOf(t, err); reason != BadSignature {
+		t.Fatalf("block with a high s signature rejected as %v, want %v", reason, BadSignature)
+	}
+}
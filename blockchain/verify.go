@@ -0,0 +1,50 @@
+package blockchain
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Validates the signatures of the transactions with a pool of workers, one per CPU.
+// Returns the error of the first bad signature found, the other workers stop early
+func verifySignatures(transactions []Transaction) error {
+	workers := runtime.NumCPU()
+	if workers > len(transactions) {
+		workers = len(transactions)
+	}
+
+	jobs := make(chan int)
+	done := make(chan struct{})
+	var once sync.Once
+	var wg sync.WaitGroup
+	var failed error
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ndx := range jobs {
+				if err := validateSignatures(transactions[ndx]); err != nil {
+					once.Do(func() {
+						failed = err
+						close(done)
+					})
+				}
+			}
+		}()
+	}
+
+	// hand out transactions until one fails
+feed:
+	for ndx := range transactions {
+		select {
+		case jobs <- ndx:
+		case <-done:
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return failed
+}
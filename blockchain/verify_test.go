@@ -0,0 +1,38 @@
+package blockchain
+
+import "testing"
+
+// returns n transfers signed by one key
+func signedTransfers(t *testing.T, n int) []Transaction {
+	t.Helper()
+	priv, addr := newKey(t)
+	_, other := newKey(t)
+	transactions := make([]Transaction, n)
+	for ndx := range transactions {
+		transactions[ndx] = signedTransfer(t, priv, addr, other, uint32(ndx+1))
+	}
+	return transactions
+}
+
+func TestVerifySignatures(t *testing.T) {
+	if err := verifySignatures(signedTransfers(t, 50)); err != nil {
+		t.Fatal(err)
+	}
+	for _, transactions := range [][]Transaction{nil, {}} {
+		if err := verifySignatures(transactions); err != nil {
+			t.Fatalf("no transactions rejected, %v", err)
+		}
+	}
+}
+
+// a single bad signature is found wherever it is among the workers' jobs
+func TestVerifySignaturesOneBad(t *testing.T) {
+	for _, bad := range []int{0, 1, 25, 49} {
+		transactions := signedTransfers(t, 50)
+		transactions[bad].Amount += 100 // the signature no longer matches the ID
+
+		if reason := reasonOf(t, verifySignatures(transactions)); reason != BadSignature {
+			t.Errorf("bad signature at %v rejected as %v, want %v", bad, reason, BadSignature)
+		}
+	}
+}
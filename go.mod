@@ -9,6 +9,8 @@ require (
 	github.com/libp2p/go-libp2p v0.9.2
 	github.com/libp2p/go-libp2p-core v0.5.6
 	github.com/multiformats/go-multiaddr v0.2.2
+	github.com/pborman/uuid v0.0.0-20170112150404-1b00554d8222
+	github.com/tyler-smith/go-bip39 v1.0.1-0.20181017060643-dbb3b84ba2ef
 	golang.org/x/crypto v0.0.0-20200510223506-06a226fb4e37
 	golang.org/x/exp v0.0.0-20190125153040-c74c464bbbf2
 	gonum.org/v1/gonum v0.7.0
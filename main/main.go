@@ -3,13 +3,18 @@ package main
 import (
 	"bufio"
 	"encoding/gob"
+	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"math/rand"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/JMWorden/int32coin/blockchain"
@@ -18,8 +23,12 @@ import (
 	"github.com/JMWorden/int32coin/p2p"
 	"github.com/JMWorden/int32coin/router"
 	"github.com/JMWorden/int32coin/wallet"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
+// passphrases are read from here when set with -passphrase-fd, otherwise prompted for
+var passphraseIn *bufio.Reader
+
 func main() {
 	port := flag.Int("port", -1, "listen port number")
 	target := flag.String("peer", "", "target peer to dial")
@@ -27,8 +36,21 @@ func main() {
 	auto := flag.Bool("auto", false, "automatically peer")
 	appendHost := flag.Bool("append-host", false, "append host address to entry point file")
 	nopeer := flag.Bool("nopeer", false, "append address to entry point file")
+	reward := flag.String("reward", "", "keystore wallet to pay mining rewards to, the root wallet if empty")
+	signFile := flag.String("sign", "", "sign a transaction file offline and exit, with the -wallet wallet")
+	signWallet := flag.String("wallet", "", "keystore wallet to sign with")
+	passphraseFD := flag.Int("passphrase-fd", -1, "read passphrases from this file descriptor, one per line")
 	flag.Parse()
 
+	if *passphraseFD >= 0 {
+		passphraseIn = bufio.NewReader(os.NewFile(uintptr(*passphraseFD), "passphrase"))
+	}
+
+	if *signFile != "" {
+		signOffline(*signFile, *signWallet)
+		return
+	}
+
 	if *port == -1 {
 		log.Fatal("No port provided with -port")
 	}
@@ -37,51 +59,177 @@ func main() {
 		log.Fatal("Must specify entry point or use automatic peering for non-entry point")
 	}
 
+	var root *wallet.Wallet
+	var passphrase string
 	if *genroot {
-		genRootTransaction(genRootWallet())
+		passphrase = newWalletPassphrase()
+		root = genRootWallet(passphrase)
+		genRootTransaction(root)
+	} else {
+		root, passphrase = readRootWallet()
 	}
 
-	s, w := startSystem(10, *port, *target, *auto, *appendHost, *nopeer)
+	ks := openKeystore(passphrase)
+	s, w, bc, t := startSystem(10, *port, *target, *auto, *appendHost, *nopeer, ks, root, *reward)
 
-	interactiveTestSystem(s, w)
+	interactiveTestSystem(s, w, bc, t, ks, root)
 
 	waitForSignal(s)
 }
 
-func genRootWallet() *wallet.Wallet {
+func genRootWallet(passphrase string) *wallet.Wallet {
 	w := wallet.NewWallet()
 	path := rootWalletPath()
 
-	file, err := os.Create(path)
-	if err != nil {
-		log.Fatal("fatal: could not create root wallet, ", err)
+	if err := w.Encrypt(passphrase); err != nil {
+		log.Fatal("fatal: could not encrypt root wallet, ", err)
 	}
-	defer file.Close()
 
-	err = gob.NewEncoder(file).Encode(w)
-	if err != nil {
+	if err := w.Save(path); err != nil {
 		log.Fatal("fatal: could not write root wallet to file, ", err)
 	}
 
 	return w
 }
 
-func readRootWallet() *wallet.Wallet {
+// returns the unlocked root wallet and the wallet passphrase. A plaintext root wallet is
+// encrypted with a new passphrase, confirmed since the file is the only copy of the key
+func readRootWallet() (*wallet.Wallet, string) {
 	path := rootWalletPath()
 
-	file, err := os.Open(path)
+	w, err := wallet.Load(path)
 	if err != nil {
 		log.Fatal("fatal: could not open root wallet, ", err)
 	}
-	defer file.Close()
 
-	w := wallet.Wallet{}
-	err = gob.NewDecoder(file).Decode(&w)
+	if !w.Encrypted() {
+		log.Println("root wallet is not encrypted, choose a wallet passphrase to encrypt it")
+		passphrase := newWalletPassphrase()
+		if err := w.Encrypt(passphrase); err != nil {
+			log.Fatal("fatal: could not encrypt root wallet, ", err)
+		}
+		if err := w.Save(path); err != nil {
+			log.Fatal("fatal: could not write root wallet to file, ", err)
+		}
+		return w, passphrase
+	}
+
+	passphrase := walletPassphrase()
+	if err := w.Unlock(passphrase); err != nil {
+		log.Fatal("fatal: could not unlock root wallet, ", err)
+	}
+
+	return w, passphrase
+}
+
+// reads a line from -passphrase-fd, or prompts on the terminal without echoing
+func readPassphrase(prompt string) (string, error) {
+	if passphraseIn != nil {
+		line, err := passphraseIn.ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		return "", errors.New("no terminal to prompt on, use -passphrase-fd")
+	}
+	fmt.Print(prompt)
+	passphrase, err := terminal.ReadPassword(fd)
+	fmt.Println()
+	return string(passphrase), err
+}
+
+// reads a new passphrase, twice when prompting
+func readNewPassphrase() (string, error) {
+	passphrase, err := readPassphrase("new passphrase: ")
 	if err != nil {
-		log.Fatal("fatal: could not write root wallet to file, ", err)
+		return "", err
+	}
+	if passphrase == "" {
+		return "", errors.New("passphrase is empty")
+	}
+	if passphraseIn == nil {
+		again, err := readPassphrase("repeat passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
+
+// returns the passphrase protecting wallet files. Keystore wallets are always encrypted,
+// there's nothing to create or send from without one
+func walletPassphrase() string {
+	passphrase, err := readPassphrase("wallet passphrase: ")
+	if err != nil {
+		log.Fatal("fatal: could not read wallet passphrase, ", err)
+	}
+	if passphrase == "" {
+		log.Fatal("fatal: wallet passphrase is empty")
+	}
+	return passphrase
+}
+
+// returns a new passphrase for wallet files
+func newWalletPassphrase() string {
+	passphrase, err := readNewPassphrase()
+	if err != nil {
+		log.Fatal("fatal: could not read wallet passphrase, ", err)
+	}
+	return passphrase
+}
+
+func openKeystore(passphrase string) *wallet.Keystore {
+	path := os.Getenv("_I32COIN_KEYSTORE_PATH")
+	if path == "" {
+		log.Fatal("fatal: could not locate keystore path")
+	}
+	// the keystore lists, renames and deletes every wallet file in its directory
+	if root := os.Getenv("_I32COIN_ROOTWALL_PATH"); root != "" &&
+		filepath.Clean(filepath.Dir(root)) == filepath.Clean(path) {
+		log.Fatal("fatal: keystore path holds the root wallet, move the keystore to its own directory")
 	}
 
-	return &w
+	ks, err := wallet.OpenKeystore(path, passphrase)
+	if err != nil {
+		log.Fatal("fatal: could not open keystore, ", err)
+	}
+	return ks
+}
+
+// signs a transaction file with a keystore wallet after confirmation, writing the signed
+// transaction next to it
+func signOffline(path string, name string) {
+	w, err := openKeystore(walletPassphrase()).Load(name)
+	if err != nil {
+		log.Fatal("fatal: could not load wallet, ", err)
+	}
+	t, err := wallet.ReadTransaction(path)
+	if err != nil {
+		log.Fatal("fatal: could not read transaction, ", err)
+	}
+
+	fmt.Println(wallet.Describe(t))
+	fmt.Printf("sign? [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if strings.TrimSpace(answer) != "y" {
+		fmt.Println("-- not signed")
+		return
+	}
+
+	if err := w.SignOffline(&t); err != nil {
+		log.Fatal("fatal: could not sign transaction, ", err)
+	}
+	if err := wallet.WriteTransaction(path+".signed", t); err != nil {
+		log.Fatal("fatal: could not write signed transaction, ", err)
+	}
+	fmt.Println("signed:", path+".signed")
 }
 
 func rootWalletPath() string {
@@ -94,7 +242,7 @@ func rootWalletPath() string {
 
 func genRootTransaction(rootWallet *wallet.Wallet) {
 	t := blockchain.NewTransaction(blockchain.RootHash(), rootWallet.Addr, 1)
-	err := t.Sign(rootWallet.Priv)
+	err := rootWallet.Sign(&t)
 	if err != nil {
 		log.Fatal("fatal: failed to create sign root transaction: ")
 	}
@@ -139,13 +287,22 @@ func rootTransactionPath() string {
 }
 
 func startSystem(amount uint32, port int, target string,
-	auto bool, appendHost bool, nopeer bool) (*router.Router, *wallet.Wallet) {
+	auto bool, appendHost bool, nopeer bool, ks *wallet.Keystore, root *wallet.Wallet,
+	reward string) (*router.Router, *wallet.Wallet, blockchain.Reader, *wallet.Tracker) {
 	r := router.NewRouter()
 
-	w := readRootWallet()
+	w := root
+	if reward != "" {
+		var err error
+		if w, err = ks.Load(reward); err != nil {
+			log.Fatal("fatal: could not load reward wallet, ", err)
+		}
+	}
 	first := readRootTransaction()
 	bc := blockchain.NewBlockchain(first)
 	m := miner.NewMiner(w)
+	t := wallet.NewTracker(bc)
+	t.Track(w)
 
 	p2p.Init(port, r.NetAdmin, r.Serv)
 
@@ -166,8 +323,9 @@ func startSystem(amount uint32, port int, target string,
 	go r.Route()
 	go m.Listen(r.MineAdmin, r.Serv)
 	go bc.Listen(r.BcAdmin, r.Serv)
+	go t.Listen(r.WalAdmin)
 
-	return r, w
+	return r, w, bc, t
 }
 
 func waitForSignal(server *router.Router) {
@@ -177,10 +335,34 @@ func waitForSignal(server *router.Router) {
 	server.Close()
 }
 
-func interactiveTestSystem(r *router.Router, w *wallet.Wallet) {
-	wallets := make(map[string]*wallet.Wallet)
+func interactiveTestSystem(r *router.Router, w *wallet.Wallet, bc blockchain.Reader, t *wallet.Tracker,
+	ks *wallet.Keystore, root *wallet.Wallet) {
+	// returns the named wallet from the keystore, "miner" is the reward wallet
+	lookup := func(name string) (*wallet.Wallet, bool) {
+		if name == "miner" {
+			return w, true
+		}
+		wal, err := ks.Load(name)
+		if err != nil {
+			fmt.Println("-- could not load wallet,", err)
+			return nil, false
+		}
+		t.Track(wal)
+		return wal, true
+	}
 
-	wallets["miner"] = w
+	// stores the derived wallets of an HD wallet as <name>-<index>
+	addHDWallets := func(name string, hd *wallet.HDWallet) error {
+		for ndx, wal := range hd.Wallets {
+			walName := fmt.Sprintf("%v-%v", name, ndx)
+			if err := ks.Add(walName, wal); err != nil {
+				return fmt.Errorf("%v: %v", walName, err)
+			}
+			t.Track(wal)
+			fmt.Printf("%v: %v\n", walName, blockchain.EncodeAddress(wal.Addr))
+		}
+		return nil
+	}
 
 	scanner := bufio.NewScanner(os.Stdin)
 	scanner.Split(bufio.ScanWords)
@@ -193,27 +375,393 @@ func interactiveTestSystem(r *router.Router, w *wallet.Wallet) {
 		case "wallet":
 			scanner.Scan()
 			input = scanner.Text()
-			wal, found := wallets[input]
+			status := "found"
+			wal, err := ks.Load(input)
+			if err == wallet.ErrNoWallet {
+				status = "created"
+				wal, err = ks.Create(input)
+			}
+			if err != nil {
+				fmt.Println("-- could not load wallet,", err)
+				break
+			}
+			t.Track(wal)
+			fmt.Printf("%v: %v\n", status, blockchain.EncodeAddress(wal.Addr))
+			break
+		case "wallets":
+			names, err := ks.List()
+			if err != nil {
+				fmt.Println("-- could not list wallets,", err)
+				break
+			}
+			for _, name := range names {
+				fmt.Println(name)
+			}
+			break
+		case "watch":
+			// watch <name> <address>, adds a watch-only wallet
+			scanner.Scan()
+			name := scanner.Text()
+			scanner.Scan()
+			addr, err := blockchain.DecodeAddress(scanner.Text())
+			if err != nil {
+				fmt.Println("-- invalid address,", err)
+				break
+			}
+			wal := wallet.NewWatchOnly(addr)
+			if err := ks.Add(name, wal); err != nil {
+				fmt.Println("-- could not add wallet,", err)
+				break
+			}
+			t.Track(wal)
+			fmt.Println("watching:", blockchain.EncodeAddress(wal.Addr))
+			break
+		case "importkey":
+			// importkey <name> hex <key> | importkey <name> eth <file>, prompts for the eth
+			// file's own passphrase
+			scanner.Scan()
+			name := scanner.Text()
+			scanner.Scan()
+			format := scanner.Text()
+			scanner.Scan()
+			arg := scanner.Text()
+			var wal *wallet.Wallet
+			var err error
+			switch format {
+			case "hex":
+				wal, err = wallet.ImportHex(blockchain.ECDSA, arg)
+				break
+			case "eth":
+				var keyJSON []byte
+				var passphrase string
+				if keyJSON, err = ioutil.ReadFile(arg); err == nil {
+					passphrase, err = readPassphrase("eth keystore passphrase: ")
+				}
+				if err == nil {
+					wal, err = wallet.ImportEthKeystore(keyJSON, passphrase)
+				}
+				break
+			default:
+				err = fmt.Errorf("unknown key format %q", format)
+			}
+			if err == nil {
+				err = ks.Add(name, wal)
+			}
+			if err != nil {
+				fmt.Println("-- could not import key,", err)
+				break
+			}
+			t.Track(wal)
+			fmt.Println("imported:", blockchain.EncodeAddress(wal.Addr))
+			break
+		case "exportkey":
+			// exportkey <name> hex | exportkey <name> eth <file>, prompts for a new passphrase
+			// for the eth file
+			scanner.Scan()
+			wal, found := lookup(scanner.Text())
+			scanner.Scan()
+			format := scanner.Text()
+			if !found {
+				break
+			}
+			switch format {
+			case "hex":
+				key, err := wal.ExportHex()
+				if err != nil {
+					fmt.Println("-- could not export key,", err)
+					break
+				}
+				fmt.Println(key)
+				break
+			case "eth":
+				scanner.Scan()
+				path := scanner.Text()
+				var keyJSON []byte
+				passphrase, err := readNewPassphrase()
+				if err == nil {
+					keyJSON, err = wal.ExportEthKeystore(passphrase)
+				}
+				if err == nil {
+					err = ioutil.WriteFile(path, keyJSON, 0600)
+				}
+				if err != nil {
+					fmt.Println("-- could not export key,", err)
+					break
+				}
+				fmt.Println("exported:", path)
+				break
+			default:
+				fmt.Println("-- unknown key format")
+			}
+			break
+		case "hdnew":
+			// hdnew <name>, creates an HD wallet and stores its first address as <name>-0.
+			// The mnemonic is shown once and is the only way to recover the addresses
+			scanner.Scan()
+			name := scanner.Text()
+			mnemonic, err := wallet.NewMnemonic()
+			var hd *wallet.HDWallet
+			if err == nil {
+				hd, err = wallet.NewHDWallet(mnemonic, "", blockchain.ECDSA)
+			}
+			if err == nil {
+				_, err = hd.NextWallet()
+			}
+			if err == nil {
+				err = addHDWallets(name, hd)
+			}
+			if err != nil {
+				fmt.Println("-- could not create hd wallet,", err)
+				break
+			}
+			fmt.Println("write down the mnemonic, it recovers every address of", name)
+			fmt.Println(mnemonic)
+			break
+		case "hdrecover":
+			// hdrecover <name> <mnemonic words>, stores the addresses of the mnemonic used in
+			// the chain as <name>-0, <name>-1, ...
+			scanner.Scan()
+			name := scanner.Text()
+			words := make([]string, wallet.MnemonicWords)
+			for ndx := range words {
+				scanner.Scan()
+				words[ndx] = scanner.Text()
+			}
+			hd, err := wallet.NewHDWallet(strings.Join(words, " "), "", blockchain.ECDSA)
+			if err == nil {
+				err = hd.ScanChain(bc)
+			}
+			if err == nil && len(hd.Wallets) == 0 {
+				_, err = hd.NextWallet() // unused so far, recover the first address
+			}
+			if err == nil {
+				err = addHDWallets(name, hd)
+			}
+			if err != nil {
+				fmt.Println("-- could not recover hd wallet,", err)
+				break
+			}
+			break
+		case "lock":
+			// lock <wallet>, removes its private key from memory until unlocked
+			scanner.Scan()
+			wal, found := lookup(scanner.Text())
 			if !found {
-				wal = wallet.NewWallet()
-				wallets[input] = wallet.NewWallet()
-				fmt.Printf("created: ")
-			} else {
-				fmt.Printf("found: ")
+				break
+			}
+			if err := wal.Lock(); err != nil {
+				fmt.Println("-- could not lock wallet,", err)
+				break
+			}
+			fmt.Println("locked")
+			break
+		case "unlock":
+			// unlock <wallet>, prompts for the wallet passphrase
+			scanner.Scan()
+			wal, found := lookup(scanner.Text())
+			if !found {
+				break
+			}
+			passphrase, err := readPassphrase("wallet passphrase: ")
+			if err == nil {
+				err = wal.Unlock(passphrase)
+			}
+			if err != nil {
+				fmt.Println("-- could not unlock wallet,", err)
+				break
+			}
+			fmt.Println("unlocked")
+			break
+		case "changepass":
+			// changepass, re-encrypts the keystore and root wallet with a new passphrase
+			old, err := readPassphrase("current passphrase: ")
+			if err != nil {
+				fmt.Println("-- could not change passphrase,", err)
+				break
+			}
+			passphrase, err := readNewPassphrase()
+			if err == nil {
+				err = ks.ChangePassphrase(old, passphrase)
+			}
+			if err == nil {
+				err = root.ChangePassphrase(old, passphrase)
+			}
+			if err == nil {
+				err = root.Save(rootWalletPath())
+			}
+			if err != nil {
+				fmt.Println("-- could not change passphrase,", err)
+				break
+			}
+			fmt.Println("passphrase changed")
+			break
+		case "rename":
+			scanner.Scan()
+			old := scanner.Text()
+			scanner.Scan()
+			if err := ks.Rename(old, scanner.Text()); err != nil {
+				fmt.Println("-- could not rename wallet,", err)
+			}
+			break
+		case "delete":
+			scanner.Scan()
+			if err := ks.Delete(scanner.Text()); err != nil {
+				fmt.Println("-- could not delete wallet,", err)
 			}
-			fmt.Println(wal.Addr)
 			break
 		case "send":
+			// send <wallet> <address> <amount>
+			scanner.Scan()
+			from, found := lookup(scanner.Text())
+			scanner.Scan()
+			to, err := blockchain.DecodeAddress(scanner.Text())
+			scanner.Scan()
+			amount, amountErr := strconv.ParseUint(scanner.Text(), 10, 32)
+			if !found {
+				break
+			}
+			if err != nil {
+				fmt.Println("-- invalid address,", err)
+				break
+			}
+			if amountErr != nil {
+				fmt.Println("-- invalid amount")
+				break
+			}
+			trans, err := wallet.Build(bc, from, to, uint32(amount))
+			if err != nil {
+				fmt.Println("-- could not send,", err)
+				break
+			}
+			fmt.Printf("sending %v with fee %v\n", trans.Amount, trans.Fee)
+			r.Serv <- messages.LocalMsg{Mtype: messages.Transaction, Transaction: trans}
+			break
+		case "export":
+			// export <wallet> <address> <amount> <file>, writes an unsigned transaction
+			scanner.Scan()
+			from, found := lookup(scanner.Text())
+			scanner.Scan()
+			to, err := blockchain.DecodeAddress(scanner.Text())
 			scanner.Scan()
-			from := wallets[scanner.Text()]
+			amount, amountErr := strconv.ParseUint(scanner.Text(), 10, 32)
 			scanner.Scan()
-			to := wallets[scanner.Text()]
+			path := scanner.Text()
+			if !found {
+				break
+			}
+			if err != nil {
+				fmt.Println("-- invalid address,", err)
+				break
+			}
+			if amountErr != nil {
+				fmt.Println("-- invalid amount")
+				break
+			}
+			trans, err := wallet.BuildUnsigned(bc, from.Addr, to, uint32(amount), wallet.EstimateFee(bc))
+			if err == nil {
+				err = wallet.WriteTransaction(path, trans)
+			}
+			if err != nil {
+				fmt.Println("-- could not export,", err)
+				break
+			}
+			fmt.Println(wallet.Describe(trans))
+			break
+		case "broadcast":
+			// broadcast <file>, sends a transaction signed offline
 			scanner.Scan()
-			amount, _ := strconv.Atoi(scanner.Text())
-			trans := blockchain.NewTransaction(from.Addr, to.Addr, uint32(amount))
-			trans.Sign(from.Priv)
+			trans, err := wallet.ReadTransaction(scanner.Text())
+			if err != nil {
+				fmt.Println("-- could not read transaction,", err)
+				break
+			}
+			if !wallet.Signed(trans) {
+				fmt.Println("-- could not broadcast,", wallet.ErrUnsigned)
+				break
+			}
+			fmt.Println(wallet.Describe(trans))
 			r.Serv <- messages.LocalMsg{Mtype: messages.Transaction, Transaction: trans}
 			break
+		case "signmsg":
+			// signmsg <wallet> <message>, see readMessage for the forms of the message
+			scanner.Scan()
+			wal, found := lookup(scanner.Text())
+			scanner.Scan()
+			msg, err := readMessage(scanner.Text())
+			if !found {
+				break
+			}
+			if err != nil {
+				fmt.Println("-- could not read message,", err)
+				break
+			}
+			sig, err := wal.SignMessage(msg)
+			if err != nil {
+				fmt.Println("-- could not sign message,", err)
+				break
+			}
+			fmt.Println(sig)
+			break
+		case "verifymsg":
+			// verifymsg <address> <signature> <message>
+			scanner.Scan()
+			addr, err := blockchain.DecodeAddress(scanner.Text())
+			scanner.Scan()
+			sig, sigErr := blockchain.ParseMessageSignature(scanner.Text())
+			scanner.Scan()
+			msg, msgErr := readMessage(scanner.Text())
+			if err != nil {
+				fmt.Println("-- invalid address,", err)
+				break
+			}
+			if sigErr != nil {
+				fmt.Println("-- invalid signature,", sigErr)
+				break
+			}
+			if msgErr != nil {
+				fmt.Println("-- could not read message,", msgErr)
+				break
+			}
+			if err := sig.VerifyMessage(msg, addr); err != nil {
+				fmt.Println("-- not verified,", err)
+				break
+			}
+			fmt.Println("verified")
+			break
+		case "fee":
+			fmt.Println(wallet.EstimateFee(bc))
+			break
+		case "snapshot":
+			// logs the hash of the snapshot, to be configured as trusted by new nodes
+			scanner.Scan()
+			height, _ := strconv.ParseUint(scanner.Text(), 10, 64)
+			r.Serv <- messages.LocalMsg{Mtype: messages.SnapshotReq, Height: height}
+			break
+		case "balance":
+			scanner.Scan()
+			wal, found := lookup(scanner.Text())
+			if !found {
+				break
+			}
+			bal := t.Balances(wal.Addr)
+			fmt.Printf("confirmed %v, pending %v, immature %v at height %v\n", bal.Confirmed, bal.Pending,
+				bal.Immature, bc.Height())
+			break
+		case "history":
+			scanner.Scan()
+			wal, found := lookup(scanner.Text())
+			if !found {
+				break
+			}
+			for _, e := range t.History(wal.Addr) {
+				fmt.Printf("%+d\t%v confirmations\t%v\n", e.Change, e.Confirmations, e.Transaction.TXID)
+			}
+			break
+		case "mempool":
+			for _, trans := range bc.Mempool() {
+				fmt.Println(trans.String())
+			}
+			break
 		case "post":
 			r.Serv <- messages.LocalMsg{Mtype: messages.GenCandidate}
 			break
@@ -232,6 +780,19 @@ func interactiveTestSystem(r *router.Router, w *wallet.Wallet) {
 	}
 }
 
+// returns the message of a signmsg or verifymsg argument. Input is read a word at a time, so
+// messages with spaces or other bytes are given as hex:<hex bytes> or file:<path>, anything
+// else is the message itself
+func readMessage(arg string) ([]byte, error) {
+	if strings.HasPrefix(arg, "hex:") {
+		return hex.DecodeString(strings.TrimPrefix(arg, "hex:"))
+	}
+	if strings.HasPrefix(arg, "file:") {
+		return ioutil.ReadFile(strings.TrimPrefix(arg, "file:"))
+	}
+	return []byte(arg), nil
+}
+
 func randomTransactions(r *router.Router, mw *wallet.Wallet) {
 	randSrc := rand.New(rand.NewSource(time.Now().UnixNano()))
 	wallets := make([]*wallet.Wallet, randSrc.Intn(10)+1)
@@ -241,7 +802,7 @@ func randomTransactions(r *router.Router, mw *wallet.Wallet) {
 
 	for _, w := range wallets {
 		trans := blockchain.NewTransaction(mw.Addr, w.Addr, uint32(randSrc.Intn(1)+1))
-		trans.Sign(mw.Priv)
+		mw.Sign(&trans)
 		r.Serv <- messages.LocalMsg{Mtype: messages.Transaction, Transaction: trans}
 	}
 
@@ -250,7 +811,7 @@ func randomTransactions(r *router.Router, mw *wallet.Wallet) {
 	from := wallets[randSrc.Intn(len(wallets))]
 	to := wallets[randSrc.Intn(len(wallets))]
 	trans := blockchain.NewTransaction(from.Addr, to.Addr, uint32(1))
-	err := trans.Sign(from.Priv)
+	err := from.Sign(&trans)
 	if err != nil {
 		log.Println("random trans error: ,", err)
 		return
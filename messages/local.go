@@ -24,6 +24,26 @@ const (
 	RangeReq
 	// Range of blocks (slice)
 	Range
+	// FilterReq requests compact filters from Height to the top of the blockchain
+	FilterReq
+	// Filters is a range of compact filters (slice)
+	Filters
+	// FilterHeaderReq requests filter headers from Height to the top of the blockchain
+	FilterHeaderReq
+	// FilterHeaders is a range of filter headers (slice) starting at Height
+	FilterHeaders
+	// RejectBlock is a block that failed validation, Err holds the reason
+	RejectBlock
+	// SnapshotReq requests the account state snapshot at Height
+	SnapshotReq
+	// Snapshot is an account state snapshot, Err is set if it couldn't be made
+	Snapshot
+	// LoadSnapshot replaces the blockchain with a snapshot from the network
+	LoadSnapshot
+	// SnapshotLoaded is the top block after loading a snapshot, Err holds why it failed
+	SnapshotLoaded
+	// RemovedBlocks signals the blockchain removed blocks from Height to the top
+	RemovedBlocks
 )
 
 // LocalMsg is administrative message sent between local go routines
@@ -31,5 +51,9 @@ type LocalMsg struct {
 	Mtype       MsgType
 	Block       interface{}
 	Transaction interface{}
+	Filters     interface{}
+	Snapshot    interface{}
 	Height      uint64
+	Peer        string // network peer a block came from, empty if local
+	Err         error
 }
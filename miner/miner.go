@@ -58,15 +58,11 @@ func (m *Miner) Mine(b *blockchain.Block, in <-chan messages.LocalMsg, out chan<
 	}
 }
 
-// Create reward transaction from 0x0 to miner for reward amount
+// Create reward transaction from 0x0 to miner for reward amount plus the fees of the block
 func (m *Miner) makeReward(b *blockchain.Block) blockchain.Transaction {
-	sender := blockchain.RootHash()
-	amount := blockchain.RewardAmount()
-	trans := blockchain.NewTransaction(sender, m.w.Addr, amount)
-	trans.Seq = 0
-	trans.Signature = blockchain.RootHash()
-
-	return trans
+	reward := blockchain.NewCoinbase(b.Height, m.w.Addr)
+	reward.Amount += uint32(blockchain.Fees(b.Transactions))
+	return reward
 }
 
 // increments nonce until working hash is found
@@ -21,6 +21,12 @@ const (
 	initConn
 	rangeReq
 	peers
+	filterReq
+	filters
+	filterHeaderReq
+	filterHeaders
+	snapshotReq
+	snapshot
 )
 
 func (t mType) String() string {
@@ -39,6 +45,18 @@ func (t mType) String() string {
 		return "remove-me"
 	case peers:
 		return "peers"
+	case filterReq:
+		return "filter-request"
+	case filters:
+		return "filters"
+	case filterHeaderReq:
+		return "filter-header-request"
+	case filterHeaders:
+		return "filter-headers"
+	case snapshotReq:
+		return "snapshot-request"
+	case snapshot:
+		return "snapshot"
 	default:
 		return "undefined"
 	}
@@ -53,14 +71,28 @@ type Msg struct {
 }
 
 type helloData struct {
-	Roots []blockchain.Hash
-	Addr  string
+	Roots  []blockchain.Hash
+	Addr   string
+	Lowest uint64 // lowest height whose block body is served, blocks below are pruned
+}
+
+type snapshotData struct {
+	Snapshot *blockchain.Snapshot
 }
 
 type peerData struct {
 	Addrs []interface{} // peer addresses
 }
 
+type filterData struct {
+	Filters []*blockchain.Filter // compact filters, in order of height
+}
+
+type filterHeaderData struct {
+	Start   uint64            // height of the first header
+	Headers []blockchain.Hash // filter headers, in order of height
+}
+
 func (m *Msg) send(encoder *gob.Encoder) error {
 	err := encoder.Encode(m)
 	if err != nil {
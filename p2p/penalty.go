@@ -0,0 +1,59 @@
+package p2p
+
+import (
+	"log"
+
+	"github.com/JMWorden/int32coin/blockchain"
+)
+
+const banScore int = 100 // misbehavior score at which a peer is disconnected and banned
+
+// misbehavior score added for a block rejected for each reason. Blocks that only lost a
+// race with another block (height or previous hash mismatch) aren't penalized
+var penalties = map[blockchain.Reason]int{
+	blockchain.Malformed:           100,
+	blockchain.BadPoW:              100,
+	blockchain.BadTarget:           100,
+	blockchain.BadMerkleRoot:       100,
+	blockchain.EmptyBlock:          20,
+	blockchain.BadReward:           100,
+	blockchain.BadSequence:         50,
+	blockchain.DuplicateTXID:       50,
+	blockchain.InsufficientBalance: 50,
+	blockchain.BadSignature:        100,
+	blockchain.SelfTransfer:        50,
+	blockchain.BadChannel:          50,
+	blockchain.BadSnapshot:         100,
+	blockchain.BadCheckpoint:       100,
+}
+
+// Adds the penalty for a rejected block to the score of the peer that sent it,
+// disconnecting and banning the peer once it reaches banScore
+func (s *TCPServer) penalize(target string, err error) {
+	reason, ok := blockchain.ReasonOf(err)
+	if target == "" || !ok {
+		return // mined locally or not a validation failure
+	}
+
+	s.scores[target] += penalties[reason]
+	log.Printf("p2p server: peer %s sent bad block (%v), score %d\n", target, reason, s.scores[target])
+
+	if s.scores[target] >= banScore {
+		log.Println("p2p server: banning peer ", target)
+		s.banned[target] = struct{}{}
+		conn, found := s.peers[target]
+		if found {
+			s.removePeer(conn)
+		}
+	}
+}
+
+// Returns true if the peer was banned for misbehavior
+func (s *TCPServer) isBanned(target interface{}) bool {
+	addr, ok := target.(string)
+	if !ok {
+		return false
+	}
+	_, banned := s.banned[addr]
+	return banned
+}
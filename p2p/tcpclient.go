@@ -2,7 +2,6 @@ package p2p
 
 import (
 	"bufio"
-	"fmt"
 	"log"
 	"net"
 
@@ -10,7 +9,7 @@ import (
 )
 
 func connect(host string, port string) {
-	hostport := fmt.Sprintf("%s:%s", host, port)
+	hostport := net.JoinHostPort(host, port)
 
 	conn, err := net.Dial("tcp", hostport)
 	if err != nil {
@@ -51,9 +51,12 @@ type TCPServer struct {
 	toPeerOut  []*Msg                     // buffer of messages to be sent to peers
 	peerOutNdx *int                       // increments everytime a new message to output is generated
 	bcHeight   uint64                     // blockchain height
+	base       uint64                     // height of the snapshot the blockchain was loaded from
 	roots      map[uint64]blockchain.Hash // merkle roots of blockchain (without genesis)
 	randSrc    rand.Source
-	pending    int // opened connections that still have an unknown id
+	pending    int                 // opened connections that still have an unknown id
+	scores     map[string]int      // misbehavior scores of peers, indexed by peer address
+	banned     map[string]struct{} // peers banned for misbehavior
 }
 
 // Init initializes TCPServer, registering structures with gob
@@ -62,6 +65,9 @@ func Init(port int, in <-chan messages.LocalMsg, out chan<- messages.LocalMsg) {
 	gob.Register(blockchain.Block{})
 	gob.Register(helloData{})
 	gob.Register(peerData{})
+	gob.Register(filterData{})
+	gob.Register(filterHeaderData{})
+	gob.Register(snapshotData{})
 	server = newTCPServer(port, in, out)
 	gossipNdxs = make([]int, gossipSize)
 	server.start()
@@ -78,6 +84,8 @@ func newTCPServer(port int, in <-chan messages.LocalMsg, out chan<- messages.Loc
 	s.targets = make([]interface{}, 0, goalNumPeers)
 	s.randSrc = rand.New(rand.NewSource(uint64(time.Now().UnixNano())))
 	s.roots = make(map[uint64]blockchain.Hash)
+	s.scores = make(map[string]int)
+	s.banned = make(map[string]struct{})
 	return &s
 }
 
@@ -219,6 +227,8 @@ func (s *TCPServer) managePeers() {
 
 	// peer awaiting range
 	var awaitingRange string
+	// peers awaiting compact filters and filter headers
+	var awaitingFilters, awaitingFilterHeaders string
 
 	for {
 		select {
@@ -247,10 +257,50 @@ func (s *TCPServer) managePeers() {
 				break
 			case messages.Range:
 				_, found := s.peers[awaitingRange]
-				if found {
+				if found && msg.Err == nil {
 					sendRange(s.peers[awaitingRange].in, msg.Block.([]*blockchain.Block))
 				}
 				break
+			case messages.Filters:
+				conn, found := s.peers[awaitingFilters]
+				if found {
+					p2pmsg.Mtype = filters
+					p2pmsg.Payload = filterData{Filters: msg.Filters.([]*blockchain.Filter)}
+					s.direct(conn, &p2pmsg)
+				}
+				break
+			case messages.FilterHeaders:
+				conn, found := s.peers[awaitingFilterHeaders]
+				if found {
+					p2pmsg.Mtype = filterHeaders
+					p2pmsg.Payload = filterHeaderData{Start: msg.Height, Headers: msg.Filters.([]blockchain.Hash)}
+					s.direct(conn, &p2pmsg)
+				}
+				break
+			case messages.RejectBlock:
+				s.penalize(msg.Peer, msg.Err)
+				break
+			case messages.Snapshot:
+				conn, found := s.peers[msg.Peer]
+				if found && msg.Err == nil {
+					p2pmsg.Mtype = snapshot
+					p2pmsg.Payload = snapshotData{Snapshot: msg.Snapshot.(*blockchain.Snapshot)}
+					s.direct(conn, &p2pmsg)
+				}
+				break
+			case messages.RemovedBlocks:
+				for h := msg.Height; h <= s.bcHeight; h++ {
+					delete(s.roots, h)
+				}
+				s.bcHeight = msg.Height - 1
+				break
+			case messages.SnapshotLoaded:
+				if msg.Err != nil {
+					s.penalize(msg.Peer, msg.Err)
+					break
+				}
+				s.handleSnapshot(msg.Block.(*blockchain.Block), msg.Peer)
+				break
 			}
 			break
 		case msg := <-s.internal:
@@ -266,7 +316,7 @@ func (s *TCPServer) managePeers() {
 				if seen {
 					break // skip if already seen
 				}
-				s.adminOut <- messages.LocalMsg{Mtype: messages.AddBlock, Block: &block}
+				s.adminOut <- messages.LocalMsg{Mtype: messages.AddBlock, Block: &block, Peer: msg.conn.target}
 				break
 			case removeMe:
 				s.removePeer(msg.conn)
@@ -291,6 +341,22 @@ func (s *TCPServer) managePeers() {
 				awaitingRange = msg.conn.target
 				s.adminOut <- messages.LocalMsg{Mtype: messages.RangeReq, Height: msg.Payload.(uint64)}
 				break
+			case snapshotReq:
+				s.adminOut <- messages.LocalMsg{Mtype: messages.SnapshotReq, Height: msg.Payload.(uint64),
+					Peer: msg.conn.target}
+				break
+			case snapshot:
+				s.adminOut <- messages.LocalMsg{Mtype: messages.LoadSnapshot,
+					Snapshot: msg.Payload.(snapshotData).Snapshot, Peer: msg.conn.target}
+				break
+			case filterReq:
+				awaitingFilters = msg.conn.target
+				s.adminOut <- messages.LocalMsg{Mtype: messages.FilterReq, Height: msg.Payload.(uint64)}
+				break
+			case filterHeaderReq:
+				awaitingFilterHeaders = msg.conn.target
+				s.adminOut <- messages.LocalMsg{Mtype: messages.FilterHeaderReq, Height: msg.Payload.(uint64)}
+				break
 			case peers:
 				s.handlePeers(msg)
 				break
@@ -414,10 +480,14 @@ func (s *TCPServer) direct(to *peerConn, msg *Msg) {
 
 func (s *TCPServer) makeHello() *Msg {
 	msg := Msg{Mtype: hello}
-	data := helloData{Addr: s.addr}
+	data := helloData{Addr: s.addr, Lowest: blockchain.LowestBody(s.bcHeight)}
+	// blocks up to the snapshot were never downloaded, only the ones after it can be served
+	if s.base > 0 && data.Lowest < s.base+1 {
+		data.Lowest = s.base + 1
+	}
 
-	data.Roots = make([]blockchain.Hash, len(s.roots)+1)
-	for h := uint64(1); h <= s.bcHeight; h++ {
+	data.Roots = make([]blockchain.Hash, s.bcHeight+1)
+	for h := s.base + 1; h <= s.bcHeight; h++ {
 		data.Roots[h] = s.roots[h]
 	}
 
@@ -430,6 +500,13 @@ func (s *TCPServer) makeHello() *Msg {
 func (s *TCPServer) registerPeer(conn *peerConn) bool {
 	peerAddr := conn.target
 
+	// Refuse peers banned for misbehavior
+	if s.isBanned(peerAddr) {
+		log.Printf("p2p server: refusing banned peer, %s", peerAddr)
+		s.removePeer(conn)
+		return false
+	}
+
 	// Abort registration if connection already exists
 	_, duplicate := s.peers[peerAddr]
 	if duplicate {
@@ -458,14 +535,39 @@ func (s *TCPServer) handleHellos(hello *Msg, resp *Msg) {
 		remoteRoots := resp.Payload.(helloData).Roots
 		localRoots := hello.Payload.(helloData).Roots
 
+		// bootstrap from the trusted snapshot instead of replaying the peer's chain
+		height, _, ok := blockchain.TrustedSnapshot()
+		if ok && s.bcHeight < height && resp.Height >= height {
+			log.Println("p2p server: requesting snapshot at ", height)
+			select {
+			case s.peers[resp.conn.target].in <- &Msg{Mtype: snapshotReq, Height: s.bcHeight, Payload: height}:
+			default:
+			}
+			return
+		}
+
 		// remove differing blocks at end of chain
 		h := uint64(hello.Height)
-		for h > 0 {
+		for h > s.base {
 			if remoteRoots[h].Equals(localRoots[h]) {
 				break
 			}
 			h--
 		}
+		if h == s.base && s.base > 0 && !remoteRoots[h].Equals(localRoots[h]) {
+			log.Println("p2p server: peer's chain forks below the snapshot")
+			return
+		}
+		if cp := blockchain.LastCheckpoint(s.bcHeight); h < cp {
+			log.Println("p2p server: peer's chain conflicts with checkpoint ", cp)
+			return
+		}
+
+		// skip peers that pruned the blocks needed to switch chains
+		if lowest := resp.Payload.(helloData).Lowest; lowest > h+1 {
+			log.Printf("p2p server: peer only serves blocks from %v, need %v\n", lowest, h+1)
+			return
+		}
 
 		// remove [h+1:end]
 		for rh := h + 1; rh <= s.bcHeight; rh++ {
@@ -484,6 +586,24 @@ func (s *TCPServer) handleHellos(hello *Msg, resp *Msg) {
 	}
 }
 
+// Records the top of the blockchain loaded from a snapshot, and requests the blocks after
+// it from the peer that sent the snapshot
+func (s *TCPServer) handleSnapshot(top *blockchain.Block, target string) {
+	s.roots = make(map[uint64]blockchain.Hash)
+	s.bcHeight = top.Height
+	s.base = top.Height
+	s.roots[s.bcHeight] = top.MerkleRoot
+
+	conn, found := s.peers[target]
+	if !found {
+		return
+	}
+	select {
+	case conn.in <- &Msg{Mtype: rangeReq, Height: s.bcHeight, Payload: s.bcHeight + 1}:
+	default:
+	}
+}
+
 func sendRange(peerChan chan<- *Msg, blocks []*blockchain.Block) {
 	log.Println("p2p server: sending block range of size", len(blocks))
 
@@ -516,7 +636,7 @@ func (s *TCPServer) handlePeers(msg *Msg) {
 
 	for _, rp := range remotePeers {
 		_, seen := s.seenPeers[rp]
-		if !seen && rp != s.addr {
+		if !seen && rp != s.addr && !s.isBanned(rp) {
 			targets = append(targets, rp)
 			log.Println("p2p server: adding target: ", rp)
 			s.seenPeers[rp] = struct{}{}
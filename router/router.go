@@ -52,6 +52,7 @@ func (s *Router) Route() {
 		case messages.ShareBlock:
 			s.NetAdmin <- msg // send verified block to be broadcase to network
 			s.MineAdmin <- localMsg{Mtype: messages.StopMine}
+			s.WalAdmin <- msg // send verified block to wallet tracker
 			break
 		case messages.Transaction:
 			s.BcAdmin <- msg
@@ -68,6 +69,29 @@ func (s *Router) Route() {
 		case messages.Range:
 			s.NetAdmin <- msg // send block range to network
 			break
+		case messages.FilterReq, messages.FilterHeaderReq:
+			s.BcAdmin <- msg // send filter range request to blockchain
+			break
+		case messages.Filters, messages.FilterHeaders:
+			s.NetAdmin <- msg // send filter range to network
+			break
+		case messages.RejectBlock:
+			s.NetAdmin <- msg // send rejected block to network, to penalize the peer
+			break
+		case messages.SnapshotReq, messages.LoadSnapshot:
+			s.BcAdmin <- msg // send snapshot request or snapshot to blockchain
+			break
+		case messages.RemovedBlocks:
+			s.NetAdmin <- msg // send removed block range to network
+			s.WalAdmin <- msg // send removed block range to wallet tracker
+			break
+		case messages.Snapshot:
+			s.NetAdmin <- msg // send snapshot to network
+			break
+		case messages.SnapshotLoaded:
+			s.NetAdmin <- msg // send loaded chain to network
+			s.WalAdmin <- msg // send loaded chain to wallet tracker
+			break
 		}
 	}
 }
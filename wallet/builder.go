@@ -0,0 +1,119 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/JMWorden/int32coin/blockchain"
+)
+
+const (
+	// MinFee is the lowest fee EstimateFee returns
+	MinFee uint32 = 1
+	// FeeWindow is the number of recent blocks EstimateFee looks at
+	FeeWindow uint64 = 20
+)
+
+var (
+	// ErrZeroAmount is returned when building a transaction that sends nothing
+	ErrZeroAmount = errors.New("amount must be positive")
+	// ErrSelfSend is returned when building a transaction to the sending wallet
+	ErrSelfSend = errors.New("can't send to the sending wallet")
+)
+
+// InsufficientFundsError is returned when a wallet can't afford a transaction
+type InsufficientFundsError struct {
+	Spendable int64 // balance less immature rewards and the pending outgoing transactions
+	Needed    int64 // amount plus fee
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("insufficient funds, %v spendable but %v needed", e.Spendable, e.Needed)
+}
+
+// EstimateFee returns the median fee of the transactions in the last FeeWindow blocks,
+// at least MinFee
+func EstimateFee(r blockchain.Reader) uint32 {
+	fees := make([]uint32, 0)
+	top := r.Height()
+	for height := top; height > 0 && top-height < FeeWindow; height-- {
+		b, err := r.BlockAt(height)
+		if err != nil || b.Transactions == nil {
+			break // below a snapshot or pruned
+		}
+		for _, trans := range b.Transactions[1:] {
+			fees = append(fees, trans.Fee)
+		}
+	}
+
+	if len(fees) == 0 {
+		return MinFee
+	}
+	sort.Slice(fees, func(i, j int) bool { return fees[i] < fees[j] })
+	if median := fees[len(fees)/2]; median > MinFee {
+		return median
+	}
+	return MinFee
+}
+
+// Spendable returns the confirmed balance of addr at the top of the chain, less what its
+// pending transactions spend. Immature rewards and pending incoming transactions aren't
+// counted
+func Spendable(r blockchain.Reader, addr blockchain.Hash) int64 {
+	bal := r.Balance(addr) - Immature(r, addr)
+	for _, trans := range r.Mempool() {
+		if change := r.BalanceChange(trans, addr); change < 0 {
+			bal += change
+		}
+	}
+	return bal
+}
+
+// Build creates and signs a transaction sending amount from the wallet, with the
+// estimated fee
+func Build(r blockchain.Reader, w *Wallet, to blockchain.Hash, amount uint32) (blockchain.Transaction, error) {
+	return BuildWithFee(r, w, to, amount, EstimateFee(r))
+}
+
+// BuildWithFee creates and signs a transaction sending amount from the wallet with a fee,
+// checking the wallet can afford both
+func BuildWithFee(r blockchain.Reader, w *Wallet, to blockchain.Hash, amount uint32,
+	fee uint32) (blockchain.Transaction, error) {
+	if w.WatchOnly() {
+		return blockchain.Transaction{}, ErrWatchOnly
+	}
+	if w.Locked() {
+		return blockchain.Transaction{}, ErrLocked
+	}
+
+	trans, err := BuildUnsigned(r, w.Addr, to, amount, fee)
+	if err != nil {
+		return blockchain.Transaction{}, err
+	}
+	if err := w.Sign(&trans); err != nil {
+		return blockchain.Transaction{}, err
+	}
+	return trans, nil
+}
+
+// BuildUnsigned creates a transaction sending amount from an address with a fee, checking
+// the address can afford both, to be signed offline
+func BuildUnsigned(r blockchain.Reader, from blockchain.Hash, to blockchain.Hash, amount uint32,
+	fee uint32) (blockchain.Transaction, error) {
+	if amount == 0 {
+		return blockchain.Transaction{}, ErrZeroAmount
+	}
+	if to.Equals(from) {
+		return blockchain.Transaction{}, ErrSelfSend
+	}
+
+	needed := int64(amount) + int64(fee)
+	if spendable := Spendable(r, from); spendable < needed {
+		return blockchain.Transaction{}, &InsufficientFundsError{Spendable: spendable, Needed: needed}
+	}
+
+	trans := blockchain.NewTransaction(from, to, amount)
+	trans.Fee = fee
+	return trans, nil
+}
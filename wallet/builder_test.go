@@ -0,0 +1,61 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/JMWorden/int32coin/blockchain"
+)
+
+// a chain where every block pays its reward to one miner, only what the builder reads
+type rewardChain struct {
+	blockchain.Reader
+	miner  blockchain.Hash
+	reward uint32
+	height uint64
+}
+
+func (c *rewardChain) Height() uint64 {
+	return c.height
+}
+
+func (c *rewardChain) BlockAt(height uint64) (*blockchain.Block, error) {
+	if height > c.height {
+		return nil, errors.New("no such block")
+	}
+	reward := blockchain.Transaction{Sender: blockchain.RootHash(), Reciever: c.miner, Amount: c.reward}
+	return &blockchain.Block{Height: height, Transactions: []blockchain.Transaction{reward}}, nil
+}
+
+func (c *rewardChain) Balance(addr blockchain.Hash) int64 {
+	if !addr.Equals(c.miner) {
+		return 0
+	}
+	return int64(c.reward) * int64(c.height)
+}
+
+func (c *rewardChain) Mempool() []blockchain.Transaction {
+	return nil
+}
+
+// rewards with fewer than CoinbaseMaturity confirmations can't be spent
+func TestSpendableImmature(t *testing.T) {
+	miner := NewWallet()
+	chain := &rewardChain{miner: miner.Addr, reward: 50, height: 12}
+
+	// blocks 4 to 12 have fewer than 10 confirmations
+	if immature := Immature(chain, miner.Addr); immature != 9*50 {
+		t.Fatalf("immature %v, want %v", immature, 9*50)
+	}
+	if spendable := Spendable(chain, miner.Addr); spendable != 3*50 {
+		t.Fatalf("spendable %v, want %v", spendable, 3*50)
+	}
+
+	_, err := BuildUnsigned(chain, miner.Addr, NewWallet().Addr, 3*50, 1)
+	if funds, ok := err.(*InsufficientFundsError); !ok || funds.Spendable != 3*50 {
+		t.Fatalf("spent an immature reward (%v)", err)
+	}
+	if _, err := BuildUnsigned(chain, miner.Addr, NewWallet().Addr, 3*50-1, 1); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,210 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/JMWorden/int32coin/blockchain"
+)
+
+// Channel is a wallet's view of a payment channel it funds or is paid through
+type Channel struct {
+	ID      blockchain.Hash          // ID of the open transaction
+	Funder  blockchain.Hash          // address that locked the deposit
+	Payee   blockchain.Hash          // address being paid through the channel
+	Deposit uint32                   // amount locked in the channel
+	Expiry  uint64                   // height from which the funder may refund the deposit
+	Paid    uint32                   // total paid to the payee in the latest state
+	Signed  *blockchain.ChannelState // latest state signed by the counterparty, nil if none
+	Closing bool                     // true once the payee acknowledged the latest state
+}
+
+func (c *Channel) String() string {
+	return fmt.Sprintf("channel %v:\n\tfunder:%v\n\tpayee:%v\n\tdeposit:%v\n\texpiry:%v\n\tpaid:%v",
+		c.ID, c.Funder, c.Payee, c.Deposit, c.Expiry, c.Paid)
+}
+
+// returns the channel with the given id, or an error if the wallet isn't part of it
+func (w *Wallet) channel(id blockchain.Hash) (*Channel, error) {
+	c, found := w.Channels[id.String()]
+	if !found {
+		return nil, errors.New("unknown channel " + id.String())
+	}
+	return c, nil
+}
+
+func (w *Wallet) addChannel(c *Channel) {
+	if w.Channels == nil { // wallets saved before channels existed
+		w.Channels = make(map[string]*Channel)
+	}
+	w.Channels[c.ID.String()] = c
+}
+
+// OpenChannel generates a signed transaction funding a channel to payee and starts tracking it
+func (w *Wallet) OpenChannel(payee blockchain.Hash, deposit uint32, expiry uint64) (blockchain.Transaction, error) {
+	t := blockchain.NewChannelOpen(w.Addr, payee, deposit, expiry)
+	if err := w.Sign(&t); err != nil {
+		return t, err
+	}
+	id, err := t.ID()
+	if err != nil {
+		return t, err
+	}
+
+	w.addChannel(&Channel{ID: id, Funder: w.Addr, Payee: payee, Deposit: deposit, Expiry: expiry})
+	return t, nil
+}
+
+// JoinChannel starts tracking a channel paying this wallet, from the funder's open transaction
+func (w *Wallet) JoinChannel(open blockchain.Transaction) (*Channel, error) {
+	if open.Kind != blockchain.ChannelOpen {
+		return nil, errors.New("not a channel open transaction")
+	}
+	if !open.Reciever.Equals(w.Addr) {
+		return nil, errors.New("channel does not pay this wallet")
+	}
+	if err := open.ValidateSignature(); err != nil {
+		return nil, err
+	}
+	id, err := open.ID()
+	if err != nil {
+		return nil, err
+	}
+
+	c := Channel{ID: id, Funder: open.Sender, Payee: open.Reciever, Deposit: open.Amount,
+		Expiry: open.Expiry}
+	w.addChannel(&c)
+	return &c, nil
+}
+
+// Pay signs a state paying amount more to the payee. The state is sent to the payee off-chain
+func (w *Wallet) Pay(id blockchain.Hash, amount uint32) (blockchain.ChannelState, error) {
+	state := blockchain.ChannelState{Channel: id}
+
+	c, err := w.channel(id)
+	if err != nil {
+		return state, err
+	}
+	if !c.Funder.Equals(w.Addr) {
+		return state, errors.New("only the funder can pay through a channel")
+	}
+	if c.Closing {
+		return state, errors.New("channel is closing, the payee acknowledged its final state")
+	}
+	if c.Deposit-c.Paid < amount {
+		return state, fmt.Errorf("channel has %v left, tried to pay %v", c.Deposit-c.Paid, amount)
+	}
+
+	state.Paid = c.Paid + amount
+	if err := w.signState(&state); err != nil {
+		return state, err
+	}
+
+	c.Paid = state.Paid
+	return state, nil
+}
+
+// Acknowledge signs the latest state for the funder, letting the funder close the channel.
+// It is the payee's last word on the channel: no payments are accepted after it, so the
+// only state the funder can close with pays everything received
+func (w *Wallet) Acknowledge(id blockchain.Hash) (blockchain.ChannelState, error) {
+	state := blockchain.ChannelState{Channel: id}
+
+	c, err := w.channel(id)
+	if err != nil {
+		return state, err
+	}
+	if !c.Payee.Equals(w.Addr) {
+		return state, errors.New("only the payee can acknowledge a channel state")
+	}
+	if c.Closing {
+		return state, errors.New("channel state already acknowledged")
+	}
+
+	state.Paid = c.Paid
+	if err := w.signState(&state); err != nil {
+		return state, err
+	}
+
+	c.Closing = true
+	return state, nil
+}
+
+// Update records a state signed by the other party of the channel. The payee accepts
+// payments until it acknowledges (the total paid can only grow), the funder accepts the
+// acknowledgement of its latest payment
+func (w *Wallet) Update(state blockchain.ChannelState) error {
+	c, err := w.channel(state.Channel)
+	if err != nil {
+		return err
+	}
+
+	counterparty := c.Funder
+	if c.Funder.Equals(w.Addr) {
+		counterparty = c.Payee
+	}
+	signer, err := state.Signer()
+	if err != nil {
+		return err
+	}
+	if !signer.Equals(counterparty) {
+		return errors.New("channel state not signed by counterparty")
+	}
+
+	if c.Payee.Equals(w.Addr) {
+		if c.Closing {
+			return errors.New("channel is closing, payments are no longer accepted")
+		}
+		if state.Paid < c.Paid {
+			return fmt.Errorf("channel state pays %v, already paid %v", state.Paid, c.Paid)
+		}
+		if state.Paid > c.Deposit {
+			return fmt.Errorf("channel deposit is %v, state pays %v", c.Deposit, state.Paid)
+		}
+	} else {
+		if state.Paid != c.Paid {
+			return fmt.Errorf("channel state pays %v, latest payment is %v", state.Paid, c.Paid)
+		}
+		c.Closing = true
+	}
+
+	c.Paid = state.Paid
+	c.Signed = &state
+	return nil
+}
+
+// CloseChannel generates a signed transaction settling the channel with the latest state
+// signed by the counterparty. The funder can close once the payee acknowledged
+func (w *Wallet) CloseChannel(id blockchain.Hash) (blockchain.Transaction, error) {
+	c, err := w.channel(id)
+	if err != nil {
+		return blockchain.Transaction{}, err
+	}
+	if c.Signed == nil {
+		return blockchain.Transaction{}, errors.New("no counterparty signature for the latest state")
+	}
+
+	counterparty := c.Funder
+	if c.Funder.Equals(w.Addr) {
+		counterparty = c.Payee
+	}
+
+	t := blockchain.NewChannelClose(w.Addr, counterparty, *c.Signed)
+	err = w.Sign(&t)
+	return t, err
+}
+
+// RefundChannel generates a signed transaction returning the deposit of an expired channel
+func (w *Wallet) RefundChannel(id blockchain.Hash) (blockchain.Transaction, error) {
+	c, err := w.channel(id)
+	if err != nil {
+		return blockchain.Transaction{}, err
+	}
+	if !c.Funder.Equals(w.Addr) {
+		return blockchain.Transaction{}, errors.New("only the funder can refund a channel")
+	}
+
+	t := blockchain.NewChannelRefund(w.Addr, c.Payee, c.ID, c.Deposit)
+	err = w.Sign(&t)
+	return t, err
+}
@@ -0,0 +1,103 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/JMWorden/int32coin/blockchain"
+)
+
+// returns a funder and payee sharing a channel of deposit 30
+func joinedChannel(t *testing.T) (*Wallet, *Wallet, blockchain.Hash) {
+	t.Helper()
+	funder, payee := NewWallet(), NewWallet()
+	open, err := funder.OpenChannel(payee.Addr, 30, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := payee.JoinChannel(open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return funder, payee, c.ID
+}
+
+// sends a payment from the funder to the payee
+func pay(t *testing.T, funder *Wallet, payee *Wallet, id blockchain.Hash, amount uint32) {
+	t.Helper()
+	state, err := funder.Pay(id, amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := payee.Update(state); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// the acknowledgement ends the payments, so the funder closes with everything paid
+func TestChannelAcknowledge(t *testing.T) {
+	funder, payee, id := joinedChannel(t)
+	pay(t, funder, payee, id, 5)
+	pay(t, funder, payee, id, 10)
+
+	ack, err := payee.Acknowledge(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := funder.Update(ack); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := funder.Pay(id, 1); err == nil {
+		t.Error("funder paid through a channel the payee acknowledged")
+	}
+	late := blockchain.ChannelState{Channel: id, Paid: 20}
+	if err := funder.signState(&late); err != nil {
+		t.Fatal(err)
+	}
+	if err := payee.Update(late); err == nil {
+		t.Error("payee accepted a payment after acknowledging")
+	}
+	if _, err := payee.Acknowledge(id); err == nil {
+		t.Error("payee acknowledged twice")
+	}
+
+	settle, err := funder.CloseChannel(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settle.Amount != 15 || !settle.Sender.Equals(funder.Addr) || !settle.Reciever.Equals(payee.Addr) {
+		t.Fatalf("funder closes paying %v, want 15", settle.Amount)
+	}
+}
+
+// the funder only takes an acknowledgement of its latest payment
+func TestChannelAcknowledgeStale(t *testing.T) {
+	funder, payee, id := joinedChannel(t)
+	pay(t, funder, payee, id, 5)
+	pay(t, funder, payee, id, 10)
+
+	stale := blockchain.ChannelState{Channel: id, Paid: 5}
+	if err := payee.signState(&stale); err != nil {
+		t.Fatal(err)
+	}
+	if err := funder.Update(stale); err == nil {
+		t.Fatal("funder accepted an acknowledgement of an old payment")
+	}
+	if _, err := funder.CloseChannel(id); err == nil {
+		t.Fatal("funder closed without an acknowledgement")
+	}
+}
+
+func TestChannelPayeeClose(t *testing.T) {
+	funder, payee, id := joinedChannel(t)
+	pay(t, funder, payee, id, 5)
+	pay(t, funder, payee, id, 10)
+
+	settle, err := payee.CloseChannel(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settle.Amount != 15 || !settle.Sender.Equals(payee.Addr) || !settle.Reciever.Equals(funder.Addr) {
+		t.Fatalf("payee closes paying %v, want 15", settle.Amount)
+	}
+}
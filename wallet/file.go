@@ -0,0 +1,256 @@
+package wallet
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/gob"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/JMWorden/int32coin/blockchain"
+	"golang.org/x/crypto/scrypt"
+)
+
+// default scrypt cost parameters, stored in each file so they can be raised later
+const (
+	scryptN   int = 1 << 15
+	scryptR   int = 8
+	scryptP   int = 1
+	keyLen    int = 32 // AES-256
+	saltLen   int = 32
+	fileMagic     = "i32wallet\x01" // prefix of encrypted wallet files, version 1
+)
+
+var (
+	// ErrLocked is returned when signing with a locked wallet
+	ErrLocked = errors.New("wallet is locked")
+	// ErrNotEncrypted is returned when saving, locking or unlocking a wallet without a passphrase
+	ErrNotEncrypted = errors.New("wallet is not encrypted")
+	// ErrPassphrase is returned when a passphrase doesn't decrypt the wallet
+	ErrPassphrase = errors.New("wrong passphrase")
+)
+
+// sealedKey is a private key encrypted with AES-256-GCM, under a key derived from the
+// passphrase with scrypt
+type sealedKey struct {
+	Salt       []byte
+	N, R, P    int
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+// walletFile is the encrypted wallet format. The wallet is stored without its private key,
+// and without a key at all if watch-only
+type walletFile struct {
+	Wallet *Wallet
+	Key    *sealedKey
+}
+
+// returns the AES-GCM cipher for a passphrase and scrypt parameters
+func newGCM(passphrase string, salt []byte, n, r, p int) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, n, r, p, keyLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypts a private key with a passphrase. The address is authenticated with the key so a
+// sealed key can't be moved to another wallet
+func seal(priv blockchain.Hash, addr blockchain.Hash, passphrase string) (*sealedKey, error) {
+	s := sealedKey{Salt: make([]byte, saltLen), N: scryptN, R: scryptR, P: scryptP}
+	if _, err := io.ReadFull(rand.Reader, s.Salt); err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(passphrase, s.Salt, s.N, s.R, s.P)
+	if err != nil {
+		return nil, err
+	}
+	s.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, s.Nonce); err != nil {
+		return nil, err
+	}
+	s.Ciphertext = gcm.Seal(nil, s.Nonce, priv, addr)
+
+	return &s, nil
+}
+
+// decrypts a private key, ErrPassphrase if the passphrase is wrong
+func (s *sealedKey) open(addr blockchain.Hash, passphrase string) (blockchain.Hash, error) {
+	gcm, err := newGCM(passphrase, s.Salt, s.N, s.R, s.P)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := gcm.Open(nil, s.Nonce, s.Ciphertext, addr)
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+	return priv, nil
+}
+
+// Encrypt protects the wallet's private key with a passphrase. The wallet stays unlocked
+func (w *Wallet) Encrypt(passphrase string) error {
+	if w.WatchOnly() {
+		return ErrWatchOnly
+	}
+	if w.Locked() {
+		return ErrLocked
+	}
+
+	sealed, err := seal(w.Priv, w.Addr, passphrase)
+	if err != nil {
+		return err
+	}
+	w.sealed = sealed
+	return nil
+}
+
+// Encrypted returns true if the wallet's private key is protected by a passphrase
+func (w *Wallet) Encrypted() bool {
+	return w.sealed != nil
+}
+
+// Locked returns true if the private key isn't available for signing
+func (w *Wallet) Locked() bool {
+	return w.Priv == nil
+}
+
+// Lock removes the private key from memory, it must be unlocked before signing again
+func (w *Wallet) Lock() error {
+	if !w.Encrypted() {
+		return ErrNotEncrypted
+	}
+
+	for i := range w.Priv {
+		w.Priv[i] = 0
+	}
+	w.Priv = nil
+	return nil
+}
+
+// Unlock decrypts the private key with the passphrase
+func (w *Wallet) Unlock(passphrase string) error {
+	if !w.Encrypted() {
+		return ErrNotEncrypted
+	}
+
+	priv, err := w.sealed.open(w.Addr, passphrase)
+	if err != nil {
+		return err
+	}
+	w.Priv = priv
+	return nil
+}
+
+// ChangePassphrase re-encrypts the private key with a new passphrase. The wallet must be
+// saved for the change to reach its file, wallets in a keystore change with
+// Keystore.ChangePassphrase
+func (w *Wallet) ChangePassphrase(old string, new string) error {
+	if !w.Encrypted() {
+		return ErrNotEncrypted
+	}
+
+	priv, err := w.sealed.open(w.Addr, old)
+	if err != nil {
+		return err
+	}
+	sealed, err := seal(priv, w.Addr, new)
+	if err != nil {
+		return err
+	}
+	w.sealed = sealed
+	return nil
+}
+
+// Save writes the encrypted (or watch-only) wallet to path, replacing the file atomically
+func (w *Wallet) Save(path string) error {
+	if !w.Encrypted() && !w.WatchOnly() {
+		return ErrNotEncrypted
+	}
+
+	public := *w
+	public.Priv = nil
+
+	buf := new(bytes.Buffer)
+	buf.WriteString(fileMagic)
+	if err := gob.NewEncoder(buf).Encode(walletFile{Wallet: &public, Key: w.sealed}); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load reads a wallet file. Encrypted wallets are returned locked; plaintext wallets
+// (the format before encryption) are returned unlocked and unencrypted, to be migrated
+func Load(path string) (*Wallet, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	magic, err := r.Peek(len(fileMagic))
+	if err != nil || string(magic) != fileMagic {
+		w := Wallet{}
+		if err := gob.NewDecoder(r).Decode(&w); err != nil {
+			return nil, err
+		}
+		return &w, nil
+	}
+
+	r.Discard(len(fileMagic))
+	wf := walletFile{}
+	if err := gob.NewDecoder(r).Decode(&wf); err != nil {
+		return nil, err
+	}
+	if wf.Wallet == nil {
+		return nil, errors.New("wallet file is missing its wallet")
+	}
+
+	w := wf.Wallet
+	w.Priv = nil
+	w.sealed = wf.Key
+	return w, nil
+}
+
+// Migrate encrypts a plaintext wallet file in place with a passphrase. Encrypted wallet
+// files are left unchanged
+func Migrate(path string, passphrase string) error {
+	w, err := Load(path)
+	if err != nil {
+		return err
+	}
+	if w.Encrypted() || w.WatchOnly() {
+		return nil
+	}
+
+	if err := w.Encrypt(passphrase); err != nil {
+		return err
+	}
+	return w.Save(path)
+}
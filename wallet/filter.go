@@ -0,0 +1,46 @@
+package wallet
+
+import (
+	"fmt"
+
+	"github.com/JMWorden/int32coin/blockchain"
+)
+
+// MatchFilter returns true if the block summarized by the filter might touch this wallet
+func (w *Wallet) MatchFilter(f *blockchain.Filter) (bool, error) {
+	return f.Match(w.Addr)
+}
+
+// ScanFilters returns the heights of blocks whose filters match any of the addresses.
+// Filters must be consecutive; their header chain is checked starting from prev, the
+// filter header of the block before the first filter (root hash before genesis). Headers
+// commit to block hashes, so filters can't be rekeyed for other blocks
+func ScanFilters(filters []*blockchain.Filter, prev blockchain.Hash,
+	addrs []blockchain.Hash) ([]uint64, error) {
+	matched := make([]uint64, 0)
+
+	for ndx, f := range filters {
+		if ndx > 0 && f.Height != filters[ndx-1].Height+1 {
+			return nil, fmt.Errorf("filter %v does not follow filter %v", f.Height, filters[ndx-1].Height)
+		}
+
+		header, err := f.CalcHeader(prev)
+		if err != nil {
+			return nil, err
+		}
+		if !header.Equals(f.Header) {
+			return nil, fmt.Errorf("filter header chain mismatch at height %v", f.Height)
+		}
+		prev = header
+
+		match, err := f.MatchAny(addrs)
+		if err != nil {
+			return nil, err
+		}
+		if match {
+			matched = append(matched, f.Height)
+		}
+	}
+
+	return matched, nil
+}
@@ -0,0 +1,233 @@
+package wallet
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/JMWorden/int32coin/blockchain"
+	"github.com/ethereum/go-ethereum/crypto"
+	bip39 "github.com/tyler-smith/go-bip39"
+)
+
+const (
+	hardened    uint32 = 1 << 31 // first hardened child index
+	purpose     uint32 = 44      // BIP44 path purpose
+	coinType    uint32 = 32      // coin type in derivation paths (not registered in SLIP-44)
+	entropyBits int    = 128     // entropy of new mnemonics, 12 words
+	// GapLimit is the number of consecutive unused addresses after which scanning stops
+	GapLimit int = 20
+	// MnemonicWords is the number of words of new mnemonics, 11 bits of entropy and checksum each
+	MnemonicWords int = (entropyBits + entropyBits/32) / 11
+)
+
+// ErrHDKeyType is returned when deriving keys for a scheme that isn't on secp256k1
+var ErrHDKeyType = errors.New("hd derivation needs a secp256k1 key type")
+
+// extendedKey is a BIP32 private key and chain code
+type extendedKey struct {
+	key   []byte // 32 byte private key
+	chain []byte // 32 byte chain code
+}
+
+// HDWallet derives wallets (addresses) from a BIP39 mnemonic with BIP32 derivation along
+// m/44'/32'/0'/0/i. The mnemonic (and password) alone recovers every address
+type HDWallet struct {
+	KeyType blockchain.KeyType // signature scheme of the derived keys
+	Wallets []*Wallet          // derived wallets, oldest first
+	next    uint32             // child index of the next address
+	account *extendedKey       // key at m/44'/32'/0'/0, the parent of every address
+}
+
+// NewMnemonic returns a new random 12 word BIP39 mnemonic
+func NewMnemonic() (string, error) {
+	entropy, err := bip39.NewEntropy(entropyBits)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// NewHDWallet creates an HD wallet from a mnemonic and optional password, deriving keys
+// of the given type. No addresses are derived until NextWallet or Scan
+func NewHDWallet(mnemonic string, password string, kt blockchain.KeyType) (*HDWallet, error) {
+	if kt != blockchain.ECDSA && kt != blockchain.Schnorr {
+		return nil, ErrHDKeyType
+	}
+
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, password)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := masterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range []uint32{purpose + hardened, coinType + hardened, hardened, 0} {
+		if key, err = key.child(index); err != nil {
+			return nil, err
+		}
+	}
+
+	return &HDWallet{KeyType: kt, Wallets: make([]*Wallet, 0), account: key}, nil
+}
+
+// derives the master key from a seed
+func masterKey(seed []byte) (*extendedKey, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	k := new(big.Int).SetBytes(sum[:32])
+	if k.Sign() == 0 || k.Cmp(crypto.S256().Params().N) >= 0 {
+		return nil, errors.New("seed derives an invalid master key")
+	}
+	return &extendedKey{key: sum[:32], chain: sum[32:]}, nil
+}
+
+// derives the private child key at index, hardened from index 2^31
+func (k *extendedKey) child(index uint32) (*extendedKey, error) {
+	curve := crypto.S256()
+
+	data := make([]byte, 0, 37)
+	if index >= hardened {
+		data = append(data, 0)
+		data = append(data, k.key...)
+	} else {
+		x, y := curve.ScalarBaseMult(k.key)
+		data = append(data, compress(x, y)...)
+	}
+	indexBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(indexBuf, index)
+	data = append(data, indexBuf...)
+
+	mac := hmac.New(sha512.New, k.chain)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	n := curve.Params().N
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(n) >= 0 {
+		return nil, fmt.Errorf("child %v is invalid", index)
+	}
+	childKey := il.Add(il, new(big.Int).SetBytes(k.key))
+	childKey.Mod(childKey, n)
+	if childKey.Sign() == 0 {
+		return nil, fmt.Errorf("child %v is invalid", index)
+	}
+
+	return &extendedKey{key: blockchain.Pad32(childKey), chain: sum[32:]}, nil
+}
+
+// returns the 33 byte compressed encoding of a curve point
+func compress(x *big.Int, y *big.Int) []byte {
+	prefix := byte(2)
+	if y.Bit(0) == 1 {
+		prefix = 3
+	}
+	return append([]byte{prefix}, blockchain.Pad32(x)...)
+}
+
+// Derive returns the wallet at child index i, without recording it
+func (h *HDWallet) Derive(i uint32) (*Wallet, error) {
+	if i >= hardened {
+		return nil, fmt.Errorf("address index %v is too large", i)
+	}
+
+	key, err := h.account.child(i)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme, err := blockchain.Scheme(h.KeyType)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := scheme.Public(key.key)
+	if err != nil {
+		return nil, err
+	}
+
+	w := Wallet{KeyType: h.KeyType, Priv: key.key, Pub: pub, Addr: blockchain.Address(h.KeyType, pub),
+		Transactions: make([]blockchain.Transaction, 0), Channels: make(map[string]*Channel)}
+	return &w, nil
+}
+
+// NextWallet derives the wallet after the last derived one, for a new receiving address.
+// Invalid child keys (probability below 2^-127) are skipped
+func (h *HDWallet) NextWallet() (*Wallet, error) {
+	for h.next < hardened {
+		w, err := h.Derive(h.next)
+		h.next++
+		if err == nil {
+			h.Wallets = append(h.Wallets, w)
+			return w, nil
+		}
+		log.Println("wallet: skipping invalid child key, ", err)
+	}
+	return nil, errors.New("no address indexes left")
+}
+
+// Scan derives addresses until GapLimit consecutive ones are unused, keeping the wallets up
+// to the last used address. Used reports whether an address appears in the chain
+func (h *HDWallet) Scan(used func(addr blockchain.Hash) (bool, error)) error {
+	h.Wallets = make([]*Wallet, 0)
+	h.next = 0
+	pending := make([]*Wallet, 0, GapLimit)
+
+	for i, gap := uint32(0), 0; gap < GapLimit && i < hardened; i++ {
+		w, err := h.Derive(i)
+		if err != nil {
+			continue // invalid child key, never used
+		}
+		pending = append(pending, w)
+
+		found, err := used(w.Addr)
+		if err != nil {
+			return err
+		}
+		if found {
+			h.Wallets = append(h.Wallets, pending...)
+			pending = pending[:0]
+			h.next = i + 1
+			gap = 0
+		} else {
+			gap++
+		}
+	}
+
+	return nil
+}
+
+// ScanChain recovers the used addresses of the wallet from the chain. It uses the address
+// index if enabled, otherwise the addresses in the blocks that weren't pruned
+func (h *HDWallet) ScanChain(r blockchain.Reader) error {
+	if _, err := r.AddressTransactions(blockchain.RootHash()); err != blockchain.ErrNotIndexed {
+		return h.Scan(func(addr blockchain.Hash) (bool, error) {
+			locs, err := r.AddressTransactions(addr)
+			return len(locs) > 0, err
+		})
+	}
+
+	seen := make(map[string]struct{})
+	for height := uint64(0); height <= r.Height(); height++ {
+		b, err := r.BlockAt(height)
+		if err != nil {
+			continue // below a loaded snapshot
+		}
+		for _, trans := range b.Transactions {
+			seen[string(trans.Sender)] = struct{}{}
+			seen[string(trans.Reciever)] = struct{}{}
+		}
+	}
+
+	return h.Scan(func(addr blockchain.Hash) (bool, error) {
+		_, found := seen[string(addr)]
+		return found, nil
+	})
+}
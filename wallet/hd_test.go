@@ -0,0 +1,193 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/JMWorden/int32coin/blockchain"
+	bip39 "github.com/tyler-smith/go-bip39"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// a derivation step of a BIP32 test vector, the key and chain code decoded from its xprv
+type bip32Step struct {
+	index uint32
+	chain string
+	key   string
+}
+
+// derives along the steps from the master key of seed, checking every key
+func checkBIP32(t *testing.T, seed string, master bip32Step, steps []bip32Step) {
+	t.Helper()
+	key, err := masterKey(unhex(t, seed))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := "m"
+	for ndx, step := range append([]bip32Step{master}, steps...) {
+		if ndx > 0 {
+			if key, err = key.child(step.index); err != nil {
+				t.Fatal(err)
+			}
+			if step.index >= hardened {
+				path += fmt.Sprintf("/%vH", step.index-hardened)
+			} else {
+				path += fmt.Sprintf("/%v", step.index)
+			}
+		}
+		if !bytes.Equal(key.key, unhex(t, step.key)) {
+			t.Errorf("%v key %x, want %v", path, key.key, step.key)
+		}
+		if !bytes.Equal(key.chain, unhex(t, step.chain)) {
+			t.Errorf("%v chain code %x, want %v", path, key.chain, step.chain)
+		}
+	}
+}
+
+func TestBIP32Vector1(t *testing.T) {
+	checkBIP32(t, "000102030405060708090a0b0c0d0e0f",
+		bip32Step{0,
+			"873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508",
+			"e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35"},
+		[]bip32Step{
+			{hardened,
+				"47fdacbd0f1097043b78c63c20c34ef4ed9a111d980047ad16282c7ae6236141",
+				"edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+			{1,
+				"2a7857631386ba23dacac34180dd1983734e444fdbf774041578e9b6adb37c19",
+				"3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368"},
+			{2 + hardened,
+				"04466b9cc8e161e966409ca52986c584f07e9dc81f735db683c3ff6ec7b1503f",
+				"cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca"},
+			{2,
+				"cfb71883f01676f587d023cc53a35bc7f88f724b1f8c2892ac1275ac822a3edd",
+				"0f479245fb19a38a1954c5c7c0ebab2f9bdfd96a17563ef28a6a4b1a2a764ef4"},
+			{1000000000,
+				"c783e67b921d2beb8f6b389cc646d7263b4145701dadd2161548a8b078e65e9e",
+				"471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8"},
+		})
+}
+
+func TestBIP32Vector2(t *testing.T) {
+	checkBIP32(t, "fffcf9f6f3f0edeae7e4e1dedbd8d5d2cfccc9c6c3c0bdbab7b4b1aeaba8a5a29f9c999693908d8a8784817e7b7875726f6c696663605d5a5754514e4b484542",
+		bip32Step{0,
+			"60499f801b896d83179a4374aeb7822aaeaceaa0db1f85ee3e904c4defbd9689",
+			"4b03d6fc340455b363f51020ad3ecca4f0850280cf436c70c727923f6db46c3e"},
+		[]bip32Step{
+			{0,
+				"f0909affaa7ee7abe5dd4e100598d4dc53cd709d5a5c2cac40e7412f232f7c9c",
+				"abe74a98f6c7eabee0428f53798f0ab8aa1bd37873999041703c742f15ac7e1e"},
+			{2147483647 + hardened,
+				"be17a268474a6bb9c61e1d720cf6215e2a88c5406c4aee7b38547f585c9a37d9",
+				"877c779ad9687164e9c2f4f0f4ff0340814392330693ce95a58fe18fd52e6e93"},
+			{1,
+				"f366f48f1ea9f2d1d3fe958c95ca84ea18e4c4ddb9366c336c927eb246fb38cb",
+				"704addf544a06e5ee4bea37098463c23613da32020d604506da8c0518e1da4b7"},
+			{2147483646 + hardened,
+				"637807030d55d01f9a0cb3a7839515d796bd07706386a6eddf06cc29a65a0e29",
+				"f1c7c871a54a804afe328b4c83a1c33b8e5ff48f5087273f04efa83b247d6a2d"},
+			{2,
+				"9452b549be8cea3ecb7a84bec10dcfd94afe4d129ebfd3b3cb58eedf394ed271",
+				"bb7d39bdb83ecf58f2fd82b6d918341cbef428661ef01ab97c28a4842125ac23"},
+		})
+}
+
+// BIP39 reference vector, the seed of the all zero entropy mnemonic with password TREZOR
+func TestBIP39Seed(t *testing.T) {
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	want := unhex(t, "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04")
+
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, "TREZOR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(seed, want) {
+		t.Fatalf("seed %x, want %x", seed, want)
+	}
+
+	h, err := NewHDWallet(mnemonic, "TREZOR", blockchain.ECDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := masterKey(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, index := range []uint32{purpose + hardened, coinType + hardened, hardened, 0} {
+		if account, err = account.child(index); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(h.account.key, account.key) || !bytes.Equal(h.account.chain, account.chain) {
+		t.Fatal("hd wallet not derived from the mnemonic's seed")
+	}
+}
+
+// a mnemonic whose checksum word is wrong is rejected
+func TestBIP39Checksum(t *testing.T) {
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon"
+	if _, err := NewHDWallet(mnemonic, "", blockchain.ECDSA); err == nil {
+		t.Fatal("mnemonic with a bad checksum accepted")
+	}
+}
+
+// a new mnemonic has MnemonicWords words, and the wallets derived from it are recovered by
+// the mnemonic after being stored in a keystore
+func TestNewMnemonic(t *testing.T) {
+	mnemonic, err := NewMnemonic()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if words := len(strings.Fields(mnemonic)); words != MnemonicWords {
+		t.Fatalf("mnemonic of %v words, want %v", words, MnemonicWords)
+	}
+
+	h, err := NewHDWallet(mnemonic, "", blockchain.ECDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := h.NextWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := tempDir(t)
+	ks, err := OpenKeystore(dir, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Add("hd-0", w); err != nil {
+		t.Fatal(err)
+	}
+
+	recovered, err := NewHDWallet(mnemonic, "", blockchain.ECDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := recovered.Derive(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := OpenKeystore(dir, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := reopened.Load("hd-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.Addr.Equals(again.Addr) || !bytes.Equal(stored.Priv, again.Priv) {
+		t.Fatal("stored wallet isn't the one the mnemonic recovers")
+	}
+}
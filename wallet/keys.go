@@ -0,0 +1,96 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/JMWorden/int32coin/blockchain"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pborman/uuid"
+)
+
+// ErrKeyType is returned when exporting a key in a format that doesn't support its scheme
+var ErrKeyType = errors.New("key format only holds ecdsa keys")
+
+// returns a wallet for a private key of the given type, deriving its public key and address
+func fromPrivate(kt blockchain.KeyType, priv blockchain.Hash) (*Wallet, error) {
+	scheme, err := blockchain.Scheme(kt)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := scheme.Public(priv)
+	if err != nil {
+		return nil, err
+	}
+
+	w := Wallet{KeyType: kt, Priv: priv, Pub: pub, Addr: blockchain.Address(kt, pub),
+		Transactions: make([]blockchain.Transaction, 0), Channels: make(map[string]*Channel)}
+	return &w, nil
+}
+
+// returns the private key, if the wallet has one available
+func (w *Wallet) private() (blockchain.Hash, error) {
+	if w.WatchOnly() {
+		return nil, ErrWatchOnly
+	}
+	if w.Locked() {
+		return nil, ErrLocked
+	}
+	return w.Priv, nil
+}
+
+// ImportHex creates a wallet from a hex encoded private key of the given type
+func ImportHex(kt blockchain.KeyType, hexKey string) (*Wallet, error) {
+	priv, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(hexKey), "0x"))
+	if err != nil {
+		return nil, err
+	}
+	if kt == blockchain.ECDSA {
+		if _, err := crypto.ToECDSA(priv); err != nil {
+			return nil, err
+		}
+	}
+	return fromPrivate(kt, priv)
+}
+
+// ExportHex returns the private key of the wallet, hex encoded
+func (w *Wallet) ExportHex() (string, error) {
+	priv, err := w.private()
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(priv), nil
+}
+
+// ImportEthKeystore creates a wallet from an Ethereum v3 keystore file (JSON) encrypted with
+// passphrase. The address is derived from the key like any ecdsa wallet, not the Ethereum
+// address in the file
+func ImportEthKeystore(keyJSON []byte, passphrase string) (*Wallet, error) {
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return fromPrivate(blockchain.ECDSA, crypto.FromECDSA(key.PrivateKey))
+}
+
+// ExportEthKeystore returns the wallet's ecdsa key as an Ethereum v3 keystore file (JSON),
+// encrypted with passphrase
+func (w *Wallet) ExportEthKeystore(passphrase string) ([]byte, error) {
+	if w.KeyType != blockchain.ECDSA {
+		return nil, ErrKeyType
+	}
+	priv, err := w.private()
+	if err != nil {
+		return nil, err
+	}
+
+	ecdsaKey, err := crypto.ToECDSA(priv)
+	if err != nil {
+		return nil, err
+	}
+	key := keystore.Key{Id: uuid.NewRandom(), Address: crypto.PubkeyToAddress(ecdsaKey.PublicKey),
+		PrivateKey: ecdsaKey}
+	return keystore.EncryptKey(&key, passphrase, keystore.StandardScryptN, keystore.StandardScryptP)
+}
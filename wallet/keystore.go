@@ -0,0 +1,280 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const walletExt = ".wallet" // extension of wallet files in a keystore
+
+var (
+	// ErrWalletExists is returned when creating or renaming to a name that is taken
+	ErrWalletExists = errors.New("wallet already exists")
+	// ErrNoWallet is returned when a named wallet isn't in the keystore
+	ErrNoWallet = errors.New("no such wallet")
+	// ErrNoPassphrase is returned when saving to a keystore opened without a passphrase
+	ErrNoPassphrase = errors.New("keystore has no passphrase")
+)
+
+// valid wallet names, which are also file names
+var walletName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Keystore is a directory of named wallets, encrypted with one passphrase unless they're
+// watch-only. It is safe for concurrent use, and returns the same wallet for a name until
+// it's deleted
+type Keystore struct {
+	mu         sync.Mutex
+	dir        string
+	passphrase string
+	open       map[string]*Wallet // unlocked wallets, indexed by name
+}
+
+// OpenKeystore opens the keystore in dir, creating the directory if needed
+func OpenKeystore(dir string, passphrase string) (*Keystore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &Keystore{dir: dir, passphrase: passphrase, open: make(map[string]*Wallet)}, nil
+}
+
+// returns the file of a named wallet
+func (ks *Keystore) path(name string) (string, error) {
+	if !walletName.MatchString(name) {
+		return "", fmt.Errorf("invalid wallet name %q", name)
+	}
+	return filepath.Join(ks.dir, name+walletExt), nil
+}
+
+// returns true if a file exists at path
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Create generates a new wallet, saved encrypted under name
+func (ks *Keystore) Create(name string) (*Wallet, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	w := NewWallet()
+	if err := ks.add(name, w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Add saves a wallet under a new name, encrypted with the keystore passphrase unless it's
+// watch-only
+func (ks *Keystore) Add(name string, w *Wallet) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	return ks.add(name, w)
+}
+
+// encrypts and saves a wallet under a new name. Must hold the lock
+func (ks *Keystore) add(name string, w *Wallet) error {
+	path, err := ks.path(name)
+	if err != nil {
+		return err
+	}
+	if exists(path) {
+		return ErrWalletExists
+	}
+	if ks.passphrase == "" && !w.WatchOnly() {
+		return ErrNoPassphrase
+	}
+
+	if !w.Encrypted() && !w.WatchOnly() {
+		if err := w.Encrypt(ks.passphrase); err != nil {
+			return err
+		}
+	}
+	if err := w.Save(path); err != nil {
+		return err
+	}
+
+	ks.open[name] = w
+	return nil
+}
+
+// List returns the names of the wallets, sorted
+func (ks *Keystore) List() ([]string, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	return ks.list()
+}
+
+// list is List without locking, must hold the lock
+func (ks *Keystore) list() ([]string, error) {
+	files, err := ioutil.ReadDir(ks.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		name := strings.TrimSuffix(file.Name(), walletExt)
+		if !file.IsDir() && strings.HasSuffix(file.Name(), walletExt) && walletName.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Load returns the named wallet, unlocked with the keystore passphrase. Plaintext wallet
+// files are encrypted in place, watch-only wallets are returned as they are
+func (ks *Keystore) Load(name string) (*Wallet, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if w, found := ks.open[name]; found {
+		return w, nil
+	}
+
+	path, err := ks.path(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists(path) {
+		return nil, ErrNoWallet
+	}
+	w, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.Encrypted() {
+		if err := w.Unlock(ks.passphrase); err != nil {
+			return nil, err
+		}
+	} else if !w.WatchOnly() && ks.passphrase != "" {
+		if err := w.Encrypt(ks.passphrase); err != nil {
+			return nil, err
+		}
+		if err := w.Save(path); err != nil {
+			return nil, err
+		}
+	}
+
+	ks.open[name] = w
+	return w, nil
+}
+
+// ChangePassphrase re-encrypts every wallet in the keystore with a new passphrase. All of
+// them are decrypted with the old passphrase before any file is rewritten. Plaintext wallet
+// files are encrypted with the new passphrase when they're loaded
+func (ks *Keystore) ChangePassphrase(old string, new string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if old != ks.passphrase {
+		return ErrPassphrase
+	}
+	if new == "" {
+		return ErrNoPassphrase
+	}
+
+	names, err := ks.list()
+	if err != nil {
+		return err
+	}
+	wallets := make(map[string]*Wallet, len(names))
+	for _, name := range names {
+		w, found := ks.open[name]
+		if !found {
+			path, err := ks.path(name)
+			if err != nil {
+				return err
+			}
+			if w, err = Load(path); err != nil {
+				return fmt.Errorf("could not load wallet %v, %v", name, err)
+			}
+		}
+		if !w.Encrypted() {
+			continue
+		}
+		if _, err := w.sealed.open(w.Addr, old); err != nil {
+			return fmt.Errorf("could not unlock wallet %v, %v", name, err)
+		}
+		wallets[name] = w
+	}
+
+	for name, w := range wallets {
+		path, err := ks.path(name)
+		if err != nil {
+			return err
+		}
+		if err := w.ChangePassphrase(old, new); err != nil {
+			return err
+		}
+		if err := w.Save(path); err != nil {
+			return err
+		}
+	}
+
+	ks.passphrase = new
+	return nil
+}
+
+// Rename moves a wallet to a new name
+func (ks *Keystore) Rename(old string, new string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	oldPath, err := ks.path(old)
+	if err != nil {
+		return err
+	}
+	newPath, err := ks.path(new)
+	if err != nil {
+		return err
+	}
+	if !exists(oldPath) {
+		return ErrNoWallet
+	}
+	if exists(newPath) {
+		return ErrWalletExists
+	}
+
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return err
+	}
+	if w, found := ks.open[old]; found {
+		delete(ks.open, old)
+		ks.open[new] = w
+	}
+	return nil
+}
+
+// Delete removes a wallet from the keystore, locking it if it was loaded. Its coins are
+// lost unless the key is backed up elsewhere
+func (ks *Keystore) Delete(name string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	path, err := ks.path(name)
+	if err != nil {
+		return err
+	}
+	if !exists(path) {
+		return ErrNoWallet
+	}
+
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	if w, found := ks.open[name]; found {
+		w.Lock()
+		delete(ks.open, name)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package wallet
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// returns a directory removed when the test ends
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// the keystore passphrase changes for every wallet, open or not
+func TestKeystoreChangePassphrase(t *testing.T) {
+	dir := tempDir(t)
+	ks, err := OpenKeystore(dir, "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Create("open"); err != nil {
+		t.Fatal(err)
+	}
+	creator, err := OpenKeystore(dir, "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := creator.Create("closed"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ks.ChangePassphrase("wrong", "new"); err != ErrPassphrase {
+		t.Fatalf("changed with the wrong passphrase (%v)", err)
+	}
+	if err := ks.ChangePassphrase("old", "new"); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenKeystore(dir, "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"open", "closed"} {
+		w, err := reopened.Load(name)
+		if err != nil {
+			t.Fatalf("could not load %v with the new passphrase, %v", name, err)
+		}
+		if w.Locked() {
+			t.Fatalf("%v still locked", name)
+		}
+	}
+
+	stale, err := OpenKeystore(dir, "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stale.Load("closed"); err == nil {
+		t.Fatal("wallet loaded with the old passphrase")
+	}
+}
+
+// a wallet under another passphrase leaves every file as it was
+func TestKeystoreChangePassphraseMismatch(t *testing.T) {
+	dir := tempDir(t)
+	other, err := OpenKeystore(dir, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Create("a"); err != nil {
+		t.Fatal(err)
+	}
+	ks, err := OpenKeystore(dir, "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Create("b"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ks.ChangePassphrase("old", "new"); err == nil {
+		t.Fatal("changed the passphrase of a keystore with a wallet it can't unlock")
+	}
+	unchanged, err := OpenKeystore(dir, "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unchanged.Load("b"); err != nil {
+		t.Fatalf("wallet rewritten after a failed change, %v", err)
+	}
+}
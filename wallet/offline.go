@@ -0,0 +1,101 @@
+package wallet
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/JMWorden/int32coin/blockchain"
+)
+
+const txFileMagic = "i32tx\x01" // prefix of transaction files, version 1
+
+var (
+	// ErrWrongSender is returned when signing a transaction sent from another address
+	ErrWrongSender = errors.New("transaction is not sent from this wallet")
+	// ErrUnsigned is returned when broadcasting a transaction that wasn't signed
+	ErrUnsigned = errors.New("transaction is not signed")
+)
+
+// txFile is the portable format of a transaction passed between an online node and an
+// offline signer, signed or not
+type txFile struct {
+	Network     string // address prefix of the network the transaction is for
+	Transaction blockchain.Transaction
+}
+
+// WriteTransaction writes a transaction file for this network
+func WriteTransaction(path string, t blockchain.Transaction) error {
+	buf := new(bytes.Buffer)
+	buf.WriteString(txFileMagic)
+	if err := gob.NewEncoder(buf).Encode(txFile{Network: blockchain.Network(), Transaction: t}); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0600)
+}
+
+// ReadTransaction reads a transaction file, rejecting files for other networks
+func ReadTransaction(path string) (blockchain.Transaction, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return blockchain.Transaction{}, err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	magic, err := r.Peek(len(txFileMagic))
+	if err != nil || string(magic) != txFileMagic {
+		return blockchain.Transaction{}, errors.New("not a transaction file")
+	}
+	r.Discard(len(txFileMagic))
+
+	tf := txFile{}
+	if err := gob.NewDecoder(r).Decode(&tf); err != nil {
+		return blockchain.Transaction{}, err
+	}
+	if tf.Network != blockchain.Network() {
+		return blockchain.Transaction{}, blockchain.ErrAddressNetwork
+	}
+	return tf.Transaction, nil
+}
+
+// Signed returns true if the transaction carries a valid signature of its sender
+func Signed(t blockchain.Transaction) bool {
+	return len(t.Signature) > 0 && t.ValidateSignature() == nil
+}
+
+// Describe returns a human readable summary of a transaction, for confirming it before
+// signing or broadcasting
+func Describe(t blockchain.Transaction) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "send %v with fee %v, %v in total\n", t.Amount, t.Fee, uint64(t.Amount)+uint64(t.Fee))
+	fmt.Fprintf(&sb, "  from %v\n", blockchain.EncodeAddress(t.Sender))
+	fmt.Fprintf(&sb, "  to   %v\n", blockchain.EncodeAddress(t.Reciever))
+	txid := t.TXID.String()
+	if len(txid) > 16 {
+		txid = txid[:16] + "..."
+	}
+	fmt.Fprintf(&sb, "  txid %v\n", txid)
+	if t.Kind != blockchain.Transfer {
+		fmt.Fprintf(&sb, "  kind %v\n", t.Kind)
+	}
+	if Signed(t) {
+		sb.WriteString("  signed")
+	} else {
+		sb.WriteString("  unsigned")
+	}
+	return sb.String()
+}
+
+// SignOffline signs a transaction read from a file, which must be sent from the wallet
+func (w *Wallet) SignOffline(t *blockchain.Transaction) error {
+	if !t.Sender.Equals(w.Addr) {
+		return ErrWrongSender
+	}
+	return w.Sign(t)
+}
@@ -0,0 +1,209 @@
+package wallet
+
+import (
+	"log"
+	"sync"
+
+	"github.com/JMWorden/int32coin/blockchain"
+	"github.com/JMWorden/int32coin/messages"
+)
+
+// CoinbaseMaturity is the number of confirmations before a block reward counts towards the
+// confirmed balance and can be spent. Until then a reorg can take the reward away
+const CoinbaseMaturity uint64 = 10
+
+// Entry is a transaction sent or recieved by a tracked address
+type Entry struct {
+	Transaction   blockchain.Transaction
+	Height        uint64 // height of the block containing it, 0 if pending
+	Confirmations uint64 // number of blocks from its block to the top, 0 if pending
+	Change        int64  // change in the address's balance
+}
+
+// Balances is the balance of an address, split by how final it is
+type Balances struct {
+	Confirmed int64 // in blocks, excluding immature rewards
+	Pending   int64 // change from transactions in the mempool
+	Immature  int64 // block rewards with fewer than CoinbaseMaturity confirmations
+}
+
+// Tracker follows the chain for the transactions of a set of wallets, keeping their
+// histories up to date as blocks are added and removed
+type Tracker struct {
+	mu      sync.Mutex
+	chain   blockchain.Reader
+	wallets map[string]*Wallet         // tracked wallets, indexed by address
+	history map[string][]Entry         // confirmed transactions, oldest first, indexed by address
+	hashes  map[uint64]blockchain.Hash // hashes of the scanned blocks, to detect reorgs
+	scanned uint64                     // number of blocks scanned
+}
+
+// NewTracker creates a tracker following chain, with no wallets
+func NewTracker(chain blockchain.Reader) *Tracker {
+	return &Tracker{chain: chain, wallets: make(map[string]*Wallet), history: make(map[string][]Entry),
+		hashes: make(map[uint64]blockchain.Hash)}
+}
+
+// Listen updates the histories whenever the chain changes
+func (t *Tracker) Listen(in <-chan messages.LocalMsg) {
+	for msg := range in {
+		switch msg.Mtype {
+		case messages.ShareBlock, messages.RemovedBlocks, messages.SnapshotLoaded:
+			t.mu.Lock()
+			t.sync()
+			t.mu.Unlock()
+			break
+		}
+	}
+}
+
+// Track adds a wallet to the tracker and scans the chain for its transactions
+func (t *Tracker) Track(w *Wallet) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, found := t.wallets[string(w.Addr)]; found {
+		return
+	}
+	t.wallets[string(w.Addr)] = w
+
+	// blocks already scanned didn't look for the new address
+	t.history = make(map[string][]Entry)
+	t.hashes = make(map[uint64]blockchain.Hash)
+	t.scanned = 0
+	t.sync()
+}
+
+// History returns the transactions of a tracked address, confirmed ones oldest first and
+// then those in the mempool
+func (t *Tracker) History(addr blockchain.Hash) []Entry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	height := t.chain.Height()
+	entries := make([]Entry, 0, len(t.history[string(addr)]))
+	for _, e := range t.history[string(addr)] {
+		if e.Height <= height {
+			e.Confirmations = height - e.Height + 1
+		}
+		entries = append(entries, e)
+	}
+	return append(entries, t.pending(addr)...)
+}
+
+// Balances returns the confirmed, pending and immature balances of a tracked address
+func (t *Tracker) Balances(addr blockchain.Hash) Balances {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	bal := Balances{Immature: Immature(t.chain, addr)}
+	bal.Confirmed = t.chain.Balance(addr) - bal.Immature
+
+	for _, e := range t.pending(addr) {
+		bal.Pending += e.Change
+	}
+
+	return bal
+}
+
+// Immature returns the block rewards paid to addr with fewer than CoinbaseMaturity
+// confirmations, which aren't spendable yet
+func Immature(r blockchain.Reader, addr blockchain.Hash) int64 {
+	immature := int64(0)
+	top := r.Height()
+	// the genesis transaction isn't a reward, it can't be reorged away
+	for height := top; height > 0 && top-height+1 < CoinbaseMaturity; height-- {
+		b, err := r.BlockAt(height)
+		if err != nil || len(b.Transactions) == 0 {
+			break // below a snapshot or pruned
+		}
+		if reward := b.Transactions[0]; reward.IsCoinbase() && reward.Reciever.Equals(addr) {
+			immature += int64(reward.Amount)
+		}
+	}
+	return immature
+}
+
+// returns the mempool transactions of addr
+func (t *Tracker) pending(addr blockchain.Hash) []Entry {
+	entries := make([]Entry, 0)
+	for _, trans := range t.chain.Mempool() {
+		if involves(trans, addr) {
+			entries = append(entries, Entry{Transaction: trans, Change: t.chain.BalanceChange(trans, addr)})
+		}
+	}
+	return entries
+}
+
+// returns true if the transaction sends from or to addr
+func involves(trans blockchain.Transaction, addr blockchain.Hash) bool {
+	return trans.Sender.Equals(addr) || trans.Reciever.Equals(addr)
+}
+
+// forgets blocks that left the chain, then scans the new ones. Must hold the lock
+func (t *Tracker) sync() {
+	height := t.chain.Height()
+
+	// walk back to the last scanned block still in the chain
+	for t.scanned > 0 {
+		top := t.scanned - 1
+		b, err := t.chain.BlockAt(top)
+		if err == nil && top <= height {
+			if hash, err := b.Hash(); err == nil && hash.Equals(t.hashes[top]) {
+				break
+			}
+		}
+		delete(t.hashes, top)
+		t.scanned = top
+	}
+	t.truncate(t.scanned)
+
+	for ; t.scanned <= height; t.scanned++ {
+		b, err := t.chain.BlockAt(t.scanned)
+		if err != nil {
+			t.hashes[t.scanned] = nil // below a loaded snapshot
+			continue
+		}
+		hash, err := b.Hash()
+		if err != nil {
+			log.Println("wallet: could not hash block, ", err)
+			return
+		}
+		t.hashes[t.scanned] = hash
+		t.scanBlock(b) // nothing is recorded from pruned blocks
+	}
+
+	for addr, w := range t.wallets {
+		w.Transactions = make([]blockchain.Transaction, 0, len(t.history[addr]))
+		for _, e := range t.history[addr] {
+			w.Transactions = append(w.Transactions, e.Transaction)
+		}
+	}
+}
+
+// records the transactions of the tracked wallets in a block
+func (t *Tracker) scanBlock(b *blockchain.Block) {
+	for _, trans := range b.Transactions {
+		for ndx, addr := range []blockchain.Hash{trans.Sender, trans.Reciever} {
+			if ndx == 1 && addr.Equals(trans.Sender) {
+				continue // already recorded for the sender
+			}
+			if _, found := t.wallets[string(addr)]; !found {
+				continue
+			}
+			t.history[string(addr)] = append(t.history[string(addr)], Entry{Transaction: trans,
+				Height: b.Height, Change: t.chain.BalanceChange(trans, addr)})
+		}
+	}
+}
+
+// removes the entries at or above height
+func (t *Tracker) truncate(height uint64) {
+	for addr, entries := range t.history {
+		keep := len(entries)
+		for keep > 0 && entries[keep-1].Height >= height {
+			keep--
+		}
+		t.history[addr] = entries[:keep]
+	}
+}
@@ -1,53 +1,88 @@
 package wallet
 
 import (
-	"crypto/ecdsa"
 	"fmt"
 	"log"
 
-	"golang.org/x/crypto/sha3"
-
 	"github.com/JMWorden/int32coin/blockchain"
-	"github.com/ethereum/go-ethereum/crypto"
 )
 
 // Wallet is contains public/private key for address
 type Wallet struct {
+	KeyType      blockchain.KeyType // signature scheme of the key pair
 	Priv         blockchain.Hash
 	Pub          blockchain.Hash          // public key
 	Addr         blockchain.Hash          // address derived from public key
 	Transactions []blockchain.Transaction // transactions sent/recieved from this wallet
+	Channels     map[string]*Channel      // payment channels this wallet is part of, indexed by id
+	sealed       *sealedKey               // private key encrypted with the passphrase, nil if unencrypted
 }
 
-// NewWallet creates a new wallet with a public/private key pair (and address)
+// NewWallet creates a new wallet with an ECDSA public/private key pair (and address)
 func NewWallet() *Wallet {
-	w := Wallet{Transactions: make([]blockchain.Transaction, 0)}
-
-	// generate private key
-	priv, err := crypto.GenerateKey()
+	w, err := NewWalletOfType(blockchain.ECDSA)
 	if err != nil {
 		log.Fatal(err)
 	}
-	privHash := blockchain.Hash(crypto.FromECDSA(priv))
-	w.Priv = privHash
+	return w
+}
+
+// NewWalletOfType creates a new wallet with a key pair of the given signature scheme
+func NewWalletOfType(kt blockchain.KeyType) (*Wallet, error) {
+	w := Wallet{KeyType: kt, Transactions: make([]blockchain.Transaction, 0),
+		Channels: make(map[string]*Channel)}
+
+	scheme, err := blockchain.Scheme(kt)
+	if err != nil {
+		return nil, err
+	}
 
-	// generate public key
-	pub, ok := priv.Public().(*ecdsa.PublicKey)
-	if !ok {
-		log.Fatal("cast to public key failed")
+	// generate key pair
+	w.Priv, w.Pub, err = scheme.NewKey()
+	if err != nil {
+		return nil, err
 	}
-	pubHash := blockchain.Hash(crypto.FromECDSAPub(pub))
-	w.Pub = pubHash
 
 	// generate address
-	hash := sha3.New256()
-	hash.Write(pubHash)
-	addr := hash.Sum(nil)
-	w.Addr = addr
+	w.Addr = blockchain.Address(kt, w.Pub)
+
+	return &w, nil
+}
 
-	return &w
+// Sign signs the transaction with the wallet's key
+func (w *Wallet) Sign(t *blockchain.Transaction) error {
+	if w.WatchOnly() {
+		return ErrWatchOnly
+	}
+	if w.Locked() {
+		return ErrLocked
+	}
+	t.KeyType = w.KeyType
+	return t.Sign(w.Priv)
+}
+
+// signs a channel state with the wallet's key
+func (w *Wallet) signState(s *blockchain.ChannelState) error {
+	if w.WatchOnly() {
+		return ErrWatchOnly
+	}
+	if w.Locked() {
+		return ErrLocked
+	}
+	s.KeyType = w.KeyType
+	return s.Sign(w.Priv)
+}
+
+// SignMessage signs an arbitrary message with the wallet's key, proving it controls the
+// address. The signature can't be used as a transaction signature
+func (w *Wallet) SignMessage(msg []byte) (*blockchain.MessageSignature, error) {
+	priv, err := w.private()
+	if err != nil {
+		return nil, err
+	}
+	return blockchain.SignMessage(msg, w.KeyType, priv)
 }
 
 func (w *Wallet) String() string {
-	return fmt.Sprintf("wallet:\n\tpriv:%v\n\taddr:%v\n\ttrans:%v", w.Priv, w.Addr, w.Transactions)
+	return fmt.Sprintf("wallet:\n\ttype:%v\n\tpriv:%v\n\taddr:%v\n\ttrans:%v", w.KeyType, w.Priv, w.Addr, w.Transactions)
 }
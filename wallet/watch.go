@@ -0,0 +1,39 @@
+package wallet
+
+import (
+	"errors"
+
+	"github.com/JMWorden/int32coin/blockchain"
+)
+
+// ErrWatchOnly is returned when signing with a watch-only wallet
+var ErrWatchOnly = errors.New("wallet is watch-only, it has no private key")
+
+// NewWatchOnly creates a wallet that follows an address without its key. The key type
+// and public key are unknown
+func NewWatchOnly(addr blockchain.Hash) *Wallet {
+	return &Wallet{Addr: addr, Transactions: make([]blockchain.Transaction, 0),
+		Channels: make(map[string]*Channel)}
+}
+
+// NewWatchOnlyPub creates a wallet that follows the address of a public key, without the
+// private key
+func NewWatchOnlyPub(kt blockchain.KeyType, pub blockchain.Hash) (*Wallet, error) {
+	scheme, err := blockchain.Scheme(kt)
+	if err != nil {
+		return nil, err
+	}
+	if err := scheme.CheckPublic(pub); err != nil {
+		return nil, err
+	}
+
+	w := NewWatchOnly(blockchain.Address(kt, pub))
+	w.KeyType = kt
+	w.Pub = pub
+	return w, nil
+}
+
+// WatchOnly returns true if the wallet has no private key, not even an encrypted one
+func (w *Wallet) WatchOnly() bool {
+	return w.Priv == nil && w.sealed == nil
+}